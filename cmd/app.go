@@ -14,17 +14,95 @@ import (
 // TODO: configurable
 var includeExts = []string{".sh", ".sql", ".json", ".yaml", ".yml", ".conf", ".ini", ".txt", ".csv"}
 
-func CreateChangelog(repoDir, oldRef, newRef string) string {
+// WorkingTree as the new ref compares the old ref with the repository directory as-is,
+// staged and unstaged changes included, instead of a checked out commit.
+const WorkingTree = ""
+
+// Options control how the changelog is rendered.
+type Options struct {
+	// GoSyntax renders funcs and methods as Go declarations, with parameter names.
+	GoSyntax bool
+
+	// Classes lists the non-public package classes (internal, command, example)
+	// reported in their own sections. Public packages are always reported.
+	Classes []string
+
+	// Platforms to snapshot the API under; empty means the host platform.
+	Platforms []diffs.Platform
+
+	// Docs captures doc comments and reports a word-level diff of those that changed.
+	Docs bool
+
+	// Interfaces outside the module to check types against; nil means diffs.DefaultInterfaces.
+	Interfaces []diffs.WellKnownInterface
+
+	// Strict fails the run when a package could not be loaded, instead of reporting it and going on.
+	Strict bool
+
+	// LinkTemplate turns source positions into links, e.g. "https://github.com/org/repo/blob/{ref}/{file}#L{line}".
+	LinkTemplate string
+
+	// Notes stores snapshots in git notes (diffs.NotesRef) besides the local cache.
+	Notes bool
+}
+
+func (o *Options) snapshotOptions() diffs.SnapshotOptions {
+	return diffs.SnapshotOptions{Platforms: o.Platforms, Docs: o.Docs, Interfaces: o.Interfaces, Notes: o.Notes}
+}
+
+// snapshotOptionsFor returns the snapshot options of a side: the working tree is never cached,
+// since its content is not that of its HEAD commit.
+func (o *Options) snapshotOptionsFor(ref string) diffs.SnapshotOptions {
+	opts := o.snapshotOptions()
+	opts.NoCache = ref == WorkingTree
+	return opts
+}
+
+// links resolves the refs that source links point to; nil when no link template is configured.
+func (o *Options) links(repoDir, oldRef, newRef string) *diffs.Links {
+	if o.LinkTemplate == "" {
+		return nil
+	}
+	if newRef == WorkingTree {
+		newRef = "HEAD" // the closest commit; lines of uncommitted edits may be off
+	}
+	return &diffs.Links{
+		Template: o.LinkTemplate,
+		OldRef:   gitutils.ResolveRef(repoDir, oldRef),
+		NewRef:   gitutils.ResolveRef(repoDir, newRef),
+	}
+}
+
+// checkLoadErrors fails the run in strict mode when a snapshot has packages that could not be loaded,
+// removing the worktrees first.
+func (o *Options) checkLoadErrors(repoDir string, worktrees []string, apis ...map[string]diffs.APIPackage) {
+	if !o.Strict {
+		return
+	}
+	failed := diffs.FailedPackages(apis...)
+	if len(failed) == 0 {
+		return
+	}
+	cleanup(repoDir, worktrees...)
+	loggr.Fatalf("strict mode: %d package(s) could not be analysed:\n%s", len(failed), strings.Join(failed, "\n"))
+}
+
+func CreateChangelog(repoDir, oldRef, newRef string, opts Options) string {
 	//  1. Concurrent checkout old/new worktrees
 	tmpOld, tmpNew := checkout(repoDir, oldRef, newRef)
-	defer gitutils.CleanupWorktree(repoDir, tmpOld)
-	defer gitutils.CleanupWorktree(repoDir, tmpNew)
+	defer cleanup(repoDir, tmpOld, tmpNew)
+
+	var sb strings.Builder
+	if moduleChangelog(&sb, repoDir, oldRef, newRef, tmpOld, tmpNew, opts) {
+		return sb.String()
+	}
 
 	//  2. Concurrent SnapshotAPI old/new
-	oldAPI, newAPI := snap(tmpOld, tmpNew)
+	oldAPI, newAPI := snap(tmpOld, tmpNew, newRef, opts)
+	opts.checkLoadErrors(repoDir, []string{tmpOld, tmpNew}, oldAPI, newAPI)
 
 	//  3. Concurrent make diffs
-	return runDiffs(repoDir, oldRef, newRef, oldAPI, newAPI, tmpOld, tmpNew)
+	return runDiffs(repoDir, oldRef, newRef, oldAPI, newAPI, tmpOld, tmpNew, opts)
 }
 
 //nolint:gocritic
@@ -53,7 +131,7 @@ func checkout(repoDir, oldRef, newRef string) (string, string) {
 				worktreeCh <- worktreeResult{"new", "", fmt.Errorf("checkout new failed: %v", r)}
 			}
 		}()
-		path := gitutils.CheckoutWorktree(repoDir, newRef)
+		path := checkoutRef(repoDir, newRef)
 		worktreeCh <- worktreeResult{"new", path, nil}
 	}()
 
@@ -75,8 +153,25 @@ func checkout(repoDir, oldRef, newRef string) (string, string) {
 	return tmpOld, tmpNew
 }
 
+// checkoutRef checks ref out in a temporary worktree; the working tree is used in place.
+func checkoutRef(repoDir, ref string) string {
+	if ref == WorkingTree {
+		return repoDir
+	}
+	return gitutils.CheckoutWorktree(repoDir, ref)
+}
+
+// cleanup removes the worktrees checked out by checkoutRef, leaving the repository itself alone.
+func cleanup(repoDir string, worktrees ...string) {
+	for _, wt := range worktrees {
+		if wt != repoDir {
+			gitutils.CleanupWorktree(repoDir, wt)
+		}
+	}
+}
+
 //nolint:gocritic
-func snap(tmpOld, tmpNew string) (map[string]diffs.APIPackage, map[string]diffs.APIPackage) {
+func snap(tmpOld, tmpNew, newRef string, opts Options) (map[string]diffs.APIPackage, map[string]diffs.APIPackage) {
 	var wgSnapshots sync.WaitGroup
 	apiOldCh := make(chan map[string]diffs.APIPackage, 1)
 	apiNewCh := make(chan map[string]diffs.APIPackage, 1)
@@ -84,11 +179,11 @@ func snap(tmpOld, tmpNew string) (map[string]diffs.APIPackage, map[string]diffs.
 	wgSnapshots.Add(2)
 	go func() {
 		defer wgSnapshots.Done()
-		apiOldCh <- diffs.SnapshotAPI(tmpOld)
+		apiOldCh <- diffs.SnapshotAPIWith(tmpOld, opts.snapshotOptions())
 	}()
 	go func() {
 		defer wgSnapshots.Done()
-		apiNewCh <- diffs.SnapshotAPI(tmpNew)
+		apiNewCh <- diffs.SnapshotAPIWith(tmpNew, opts.snapshotOptionsFor(newRef))
 	}()
 
 	wgSnapshots.Wait()
@@ -105,6 +200,7 @@ func runDiffs(
 	repoDir, oldRef, newRef string,
 	oldAPI, newAPI map[string]diffs.APIPackage,
 	tmpOld, tmpNew string,
+	opts Options,
 ) string {
 	var wgDiffs sync.WaitGroup
 	apiDiffCh := make(chan string, 1)
@@ -116,8 +212,7 @@ func runDiffs(
 	wgDiffs.Add(1)
 	go func() {
 		defer wgDiffs.Done()
-		apiDiffResult := diffs.DiffAPI(oldAPI, newAPI)
-		apiDiffCh <- apiDiffResult.String() + "\n"
+		apiDiffCh <- diffAPI(oldAPI, newAPI, opts, opts.links(repoDir, oldRef, newRef)) + "\n"
 	}()
 
 	// Docs diff
@@ -132,7 +227,7 @@ func runDiffs(
 	wgDiffs.Add(1)
 	go func() {
 		defer wgDiffs.Done()
-		modDiffs := diffs.DiffGoMod(tmpOld, tmpNew)
+		modDiffs := diffGoMod(oldAPI, newAPI, tmpOld, tmpNew)
 		modsDiffCh <- modDiffs.String() + "\n"
 	}()
 
@@ -160,3 +255,29 @@ func runDiffs(
 
 	return sb.String()
 }
+
+// diffGoMod diffs the go.mod files, flagging changed dependencies whose types the public packages expose.
+// Internal and other packages are left out: their users are not affected by the dependency.
+func diffGoMod(oldAPI, newAPI map[string]diffs.APIPackage, oldDir, newDir string) diffs.GoModDiff {
+	modDiffs := diffs.DiffGoMod(oldDir, newDir)
+	modDiffs.Exposed = diffs.DiffExposedDependencies(
+		diffs.FilterClass(oldAPI, diffs.ClassPublic), diffs.FilterClass(newAPI, diffs.ClassPublic), oldDir, newDir)
+	return modDiffs
+}
+
+// diffAPI reports public packages in full, followed by a section for each other requested class.
+func diffAPI(oldAPI, newAPI map[string]diffs.APIPackage, opts Options, links *diffs.Links) string {
+	apiDiffResult := diffs.DiffAPI(diffs.FilterClass(oldAPI, diffs.ClassPublic), diffs.FilterClass(newAPI, diffs.ClassPublic))
+	apiDiffResult.GoSyntax = opts.GoSyntax
+	apiDiffResult.Links = links
+
+	var sb strings.Builder
+	sb.WriteString(apiDiffResult.String())
+	for _, class := range opts.Classes {
+		classDiff := diffs.DiffAPI(diffs.FilterClass(oldAPI, class), diffs.FilterClass(newAPI, class))
+		classDiff.GoSyntax = opts.GoSyntax
+		classDiff.Links = links
+		sb.WriteString(classDiff.ClassSection(class))
+	}
+	return sb.String()
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/hashmap-kz/relimpact/internal/diffs"
 	"github.com/hashmap-kz/relimpact/internal/testutils"
 
 	"github.com/stretchr/testify/assert"
@@ -69,9 +70,65 @@ This is v2.
 	testutils.RunGit(t, tmpDir, "commit", "-m", "add Bar and update docs and config")
 
 	// CreateChangelog
-	changelog := CreateChangelog(tmpDir, "v1", "HEAD")
+	changelog := CreateChangelog(tmpDir, "v1", "HEAD", Options{})
 
 	assert.Contains(t, changelog, "Bar()")
 	assert.Contains(t, changelog, "New Section")
 	assert.Contains(t, changelog, "config.yaml")
 }
+
+func TestCreateChangelog_WorkingTree(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testutils.RunGit(t, tmpDir, "init")
+	testutils.RunGit(t, tmpDir, "config", "user.name", "Test User")
+	testutils.RunGit(t, tmpDir, "config", "user.email", "test@example.com")
+	testutils.RunGo(t, tmpDir, "mod", "init", "mypkg")
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "foo.go"), []byte("package mypkg\n\nfunc Foo() {}\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(`key: value1`), 0o600))
+	testutils.RunGit(t, tmpDir, "add", "-A")
+	testutils.RunGit(t, tmpDir, "commit", "-m", "v1")
+	testutils.RunGit(t, tmpDir, "tag", "v1")
+
+	// uncommitted: an unstaged edit, a staged file and an untracked one
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "foo.go"), []byte("package mypkg\n\nfunc Foo(n int) {}\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "bar.go"), []byte("package mypkg\n\nfunc Bar() {}\n"), 0o600))
+	testutils.RunGit(t, tmpDir, "add", "bar.go")
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(`key: value2`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "schema.sql"), []byte(`SELECT 1;`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "NOTES.md"), []byte("# Draft Notes\n"), 0o600))
+	// ignored: a report of a previous run must not show up as a doc change
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte("release-impact.md\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "release-impact.md"), []byte("# Stale Report\n"), 0o600))
+
+	changelog := CreateChangelogSequential(tmpDir, "v1", WorkingTree, Options{})
+
+	assert.Contains(t, changelog, "Bar()")
+	assert.Contains(t, changelog, "Foo(int)")
+	assert.Contains(t, changelog, "config.yaml")
+	assert.Contains(t, changelog, "schema.sql")
+	assert.Contains(t, changelog, "Draft Notes")
+	assert.NotContains(t, changelog, "release-impact.md")
+	assert.NotContains(t, changelog, "Stale Report")
+	assert.FileExists(t, filepath.Join(tmpDir, "foo.go"), "the working tree is not removed like a worktree")
+}
+
+func TestDiffGoMod_InternalExposure(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(oldDir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n\nrequire github.com/dep/x v1.0.0\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(newDir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n\nrequire github.com/dep/x v1.2.0\n"), 0o600))
+
+	api := map[string]diffs.APIPackage{
+		"example.com/m/api":          {Class: diffs.ClassPublic},
+		"example.com/m/internal/cli": {Class: diffs.ClassInternal, Exposes: map[string][]string{"Run": {"github.com/dep/x"}}},
+	}
+
+	modDiffs := diffGoMod(api, api, oldDir, newDir)
+	assert.Empty(t, modDiffs.Exposed, "only an internal package exposes the dependency")
+	assert.NotContains(t, modDiffs.String(), "Exposed dependencies")
+
+	api["example.com/m/api"] = diffs.APIPackage{Class: diffs.ClassPublic, Exposes: map[string][]string{"New": {"github.com/dep/x"}}}
+	modDiffs = diffGoMod(api, api, oldDir, newDir)
+	require.Len(t, modDiffs.Exposed, 1)
+	assert.Equal(t, []string{"example.com/m/api.New"}, modDiffs.Exposed[0].Symbols)
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashmap-kz/relimpact/internal/diffs"
+	"github.com/hashmap-kz/relimpact/internal/gitutils"
+	"github.com/hashmap-kz/relimpact/internal/loggr"
+)
+
+// CacheList renders the cached snapshots with their size, age and the refs of repoDir that resolve to them.
+func CacheList(repoDir string, now time.Time) string {
+	entries, err := diffs.ListCache(diffs.CacheDir())
+	if err != nil {
+		loggr.Fatalf("cannot list cache: %v", err)
+	}
+	refs := gitutils.RefsByCommit(repoDir)
+
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SHA\tVARIANT\tSIZE\tAGE\tLAST USED\tREFS")
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.SHA, orDash(e.Variant), diffs.FormatSize(e.Size),
+			formatAge(now.Sub(e.Created)), formatAge(now.Sub(e.LastUsed)), orDash(strings.Join(refs[e.SHA], ", ")))
+	}
+	_ = w.Flush()
+	sb.WriteString(fmt.Sprintf("\n%d entries, %s in %s\n", len(entries), diffs.FormatSize(total), diffs.CacheDir()))
+	return sb.String()
+}
+
+// CachePrune removes the cached snapshots selected by policy and reports what was freed.
+func CachePrune(policy diffs.CachePrunePolicy, now time.Time) string {
+	removed, err := diffs.PruneCache(diffs.CacheDir(), policy, now)
+	if err != nil {
+		loggr.Fatalf("cannot prune cache: %v", err)
+	}
+	var sb strings.Builder
+	var freed int64
+	for _, e := range removed {
+		freed += e.Size
+		sb.WriteString(fmt.Sprintf("removed %s (%s, last used %s ago)\n", entryKey(e), diffs.FormatSize(e.Size), formatAge(now.Sub(e.LastUsed))))
+	}
+	sb.WriteString(fmt.Sprintf("pruned %d entries, freed %s\n", len(removed), diffs.FormatSize(freed)))
+	return sb.String()
+}
+
+// CacheVerify checks every cached snapshot, removing invalid ones when remove is set.
+// It returns the report and whether all entries were valid.
+func CacheVerify(remove bool) (string, bool) {
+	entries, err := diffs.ListCache(diffs.CacheDir())
+	if err != nil {
+		loggr.Fatalf("cannot list cache: %v", err)
+	}
+	var sb strings.Builder
+	invalid := 0
+	for _, e := range entries {
+		reason := diffs.VerifyCache(e.Path)
+		if reason == "" {
+			continue
+		}
+		invalid++
+		action := ""
+		if remove {
+			if err := os.Remove(e.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				loggr.Fatalf("cannot remove %s: %v", e.Path, err)
+			}
+			action = ", removed"
+		}
+		sb.WriteString(fmt.Sprintf("invalid %s: %s%s\n", entryKey(e), reason, action))
+	}
+	sb.WriteString(fmt.Sprintf("verified %d entries, %d invalid\n", len(entries), invalid))
+	return sb.String(), invalid == 0 || remove
+}
+
+// CacheClear removes every cached snapshot.
+func CacheClear() string {
+	n, err := diffs.ClearCache(diffs.CacheDir())
+	if err != nil {
+		loggr.Fatalf("cannot clear cache: %v", err)
+	}
+	return fmt.Sprintf("removed %d entries from %s\n", n, diffs.CacheDir())
+}
+
+// CapCache evicts the least recently used snapshots until the cache fits in diffs.CacheMaxSize.
+// It runs at the end of every changelog, so a shared cache dir cannot grow without bound.
+func CapCache() {
+	maxSize, err := diffs.CacheMaxSize()
+	if err != nil {
+		loggr.Warnf("ignoring RELIMPACT_API_CACHE_MAX_SIZE: %v", err)
+		maxSize = diffs.DefaultCacheMaxSize
+	}
+	if maxSize == 0 {
+		return
+	}
+	removed, err := diffs.PruneCache(diffs.CacheDir(), diffs.CachePrunePolicy{MaxSize: maxSize}, time.Now())
+	if err != nil {
+		loggr.Warnf("cannot cap cache: %v", err)
+		return
+	}
+	if len(removed) > 0 {
+		loggr.Debugf("cache over %s, evicted %d entries", diffs.FormatSize(maxSize), len(removed))
+	}
+}
+
+func entryKey(e diffs.CacheEntry) string {
+	if e.Variant == "" {
+		return e.SHA
+	}
+	return e.SHA + "-" + e.Variant
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// formatAge renders a duration in its largest whole unit, e.g. 3d or 5h.
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d/time.Hour))
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", int(d/time.Minute))
+	}
+	return fmt.Sprintf("%ds", max(0, int(d/time.Second)))
+}
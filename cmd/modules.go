@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashmap-kz/relimpact/internal/diffs"
+	"github.com/hashmap-kz/relimpact/internal/loggr"
+)
+
+// moduleChangelog reports a repository with nested modules or a go.work: a modules summary,
+// then API, go.mod and other file changes for each module, then files outside every module
+// and repository-wide documentation. It returns false when the repository is a single root module.
+func moduleChangelog(sb *strings.Builder, repoDir, oldRef, newRef, tmpOld, tmpNew string, opts Options) bool {
+	oldMods := diffs.DiscoverModules(tmpOld)
+	newMods := diffs.DiscoverModules(tmpNew)
+	if !diffs.IsMultiModule(oldMods, newMods) {
+		return false
+	}
+
+	modsDiff := diffs.DiffModules(oldMods, newMods)
+	sb.WriteString(modsDiff.String())
+	sb.WriteString("\n")
+
+	// an empty directory stands in for a module that does not exist on one side
+	emptyDir, err := os.MkdirTemp("", "relimpact-empty-")
+	if err != nil {
+		loggr.Fatalf("cannot create temp dir: %v", err)
+	}
+	defer os.RemoveAll(emptyDir)
+	if err := os.WriteFile(filepath.Join(emptyDir, "go.mod"), nil, 0o600); err != nil {
+		loggr.Fatalf("cannot create empty go.mod: %v", err)
+	}
+
+	type pair struct{ old, new *diffs.Module }
+	pairs := make(map[string]*pair)
+	for i := range oldMods {
+		pairs[oldMods[i].Path] = &pair{old: &oldMods[i]}
+	}
+	for i := range newMods {
+		if p, ok := pairs[newMods[i].Path]; ok {
+			p.new = &newMods[i]
+		} else {
+			pairs[newMods[i].Path] = &pair{new: &newMods[i]}
+		}
+	}
+	paths := make([]string, 0, len(pairs))
+	for path := range pairs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	// files are attributed to the module that owns them on the side they exist on
+	allMods := append(append([]diffs.Module{}, oldMods...), newMods...)
+	otherFiles := diffs.DiffOther(repoDir, oldRef, newRef, includeExts)
+	links := opts.links(repoDir, oldRef, newRef)
+
+	for _, path := range paths {
+		p := pairs[path]
+		oldDir, newDir := emptyDir, emptyDir
+		oldAPI, newAPI := map[string]diffs.APIPackage{}, map[string]diffs.APIPackage{}
+		var dirs []string
+		var moduleLinks *diffs.Links
+		if links != nil {
+			l := *links
+			moduleLinks = &l
+		}
+		if p.old != nil {
+			oldDir = filepath.Join(tmpOld, p.old.Dir)
+			oldAPI = diffs.SnapshotAPIWith(oldDir, opts.snapshotOptions())
+			dirs = append(dirs, p.old.Dir)
+			if moduleLinks != nil {
+				moduleLinks.OldDir = p.old.Dir
+			}
+		}
+		if p.new != nil {
+			newDir = filepath.Join(tmpNew, p.new.Dir)
+			newAPI = diffs.SnapshotAPIWith(newDir, opts.snapshotOptionsFor(newRef))
+			dirs = append(dirs, p.new.Dir)
+			if moduleLinks != nil {
+				moduleLinks.NewDir = p.new.Dir
+			}
+		}
+
+		opts.checkLoadErrors(repoDir, []string{tmpOld, tmpNew}, oldAPI, newAPI)
+
+		sb.WriteString(fmt.Sprintf("\n---\n# Module `%s`\n\n", path))
+		sb.WriteString(diffAPI(oldAPI, newAPI, opts, moduleLinks))
+		sb.WriteString("\n")
+
+		modDiffs := diffGoMod(oldAPI, newAPI, oldDir, newDir)
+		sb.WriteString(modDiffs.String())
+		sb.WriteString("\n")
+
+		owned := otherFiles.Filter(func(file string) bool {
+			owner := diffs.OwnerModule(file, allMods)
+			for _, dir := range dirs {
+				if owner == dir {
+					return true
+				}
+			}
+			return false
+		})
+		sb.WriteString(owned.String())
+		sb.WriteString("\n")
+	}
+
+	outside := otherFiles.Filter(func(file string) bool {
+		return diffs.OwnerModule(file, allMods) == ""
+	})
+	if len(outside.Diffs) > 0 {
+		sb.WriteString("\n---\n# Outside Modules\n\n")
+		sb.WriteString(outside.String())
+		sb.WriteString("\n")
+	}
+
+	docsDiffs := diffs.DiffDocs(tmpOld, tmpNew)
+	sb.WriteString(diffs.FormatAllDocDiffs(docsDiffs))
+	sb.WriteString("\n")
+	return true
+}
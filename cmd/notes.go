@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/hashmap-kz/relimpact/internal/diffs"
+	"github.com/hashmap-kz/relimpact/internal/gitutils"
+	"github.com/hashmap-kz/relimpact/internal/loggr"
+)
+
+// PublishNotes pushes the snapshots stored in git notes to remote, after merging the ones already there.
+func PublishNotes(repoDir, remote string) string {
+	msg := FetchNotes(repoDir, remote)
+	gitutils.PushNotes(repoDir, remote, diffs.NotesRef)
+	return msg + fmt.Sprintf("pushed %s to %s\n", diffs.NotesRef, remote)
+}
+
+// FetchNotes merges the snapshots stored in git notes of remote into the local ones.
+// Notes of the same commit are merged by variant.
+func FetchNotes(repoDir, remote string) string {
+	tracking, ok := gitutils.FetchNotes(repoDir, remote, diffs.NotesRef)
+	if !ok {
+		return fmt.Sprintf("%s has no %s yet\n", remote, diffs.NotesRef)
+	}
+	updated, err := diffs.MergeNotes(repoDir, tracking)
+	if err != nil {
+		loggr.Fatalf("cannot merge notes of %s: %v", remote, err)
+	}
+	return fmt.Sprintf("fetched %s from %s, merged %d notes\n", diffs.NotesRef, remote, updated)
+}
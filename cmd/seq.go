@@ -7,24 +7,28 @@ import (
 	"github.com/hashmap-kz/relimpact/internal/gitutils"
 )
 
-func CreateChangelogSequential(repoDir, oldRef, newRef string) string {
+func CreateChangelogSequential(repoDir, oldRef, newRef string, opts Options) string {
 	// Checkout old/new worktrees
 	tmpOld := gitutils.CheckoutWorktree(repoDir, oldRef)
-	defer gitutils.CleanupWorktree(repoDir, tmpOld)
+	defer cleanup(repoDir, tmpOld)
 
-	tmpNew := gitutils.CheckoutWorktree(repoDir, newRef)
-	defer gitutils.CleanupWorktree(repoDir, tmpNew)
+	tmpNew := checkoutRef(repoDir, newRef)
+	defer cleanup(repoDir, tmpNew)
+
+	var sb strings.Builder
+	if moduleChangelog(&sb, repoDir, oldRef, newRef, tmpOld, tmpNew, opts) {
+		return sb.String()
+	}
 
 	// Snapshot API
-	oldAPI := diffs.SnapshotAPI(tmpOld)
-	newAPI := diffs.SnapshotAPI(tmpNew)
+	oldAPI := diffs.SnapshotAPIWith(tmpOld, opts.snapshotOptions())
+	newAPI := diffs.SnapshotAPIWith(tmpNew, opts.snapshotOptionsFor(newRef))
+	opts.checkLoadErrors(repoDir, []string{tmpOld, tmpNew}, oldAPI, newAPI)
 
 	// Run diffs
-	var sb strings.Builder
 
 	// API diff
-	apiDiffResult := diffs.DiffAPI(oldAPI, newAPI)
-	sb.WriteString(apiDiffResult.String())
+	sb.WriteString(diffAPI(oldAPI, newAPI, opts, opts.links(repoDir, oldRef, newRef)))
 	sb.WriteString("\n")
 
 	// Docs diff
@@ -33,7 +37,7 @@ func CreateChangelogSequential(repoDir, oldRef, newRef string) string {
 	sb.WriteString("\n")
 
 	// go.mod diff
-	modDiffs := diffs.DiffGoMod(tmpOld, tmpNew)
+	modDiffs := diffGoMod(oldAPI, newAPI, tmpOld, tmpNew)
 	sb.WriteString(modDiffs.String())
 	sb.WriteString("\n")
 
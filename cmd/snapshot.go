@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/hashmap-kz/relimpact/internal/diffs"
+	"github.com/hashmap-kz/relimpact/internal/gitutils"
+	"github.com/hashmap-kz/relimpact/internal/loggr"
+)
+
+// ExportSnapshot snapshots the API of repoDir at ref, to be diffed later by DiffSnapshots.
+func ExportSnapshot(repoDir, ref string, opts Options) *diffs.SnapshotFile {
+	tmp := gitutils.CheckoutWorktree(repoDir, ref)
+	defer gitutils.CleanupWorktree(repoDir, tmp)
+
+	f := diffs.NewSnapshotFile(tmp, ref, opts.snapshotOptions())
+	opts.checkLoadErrors(repoDir, []string{tmp}, f.API)
+	return f
+}
+
+// DiffSnapshots renders the API section of the changelog from two exported snapshots.
+// Source links point to the commits recorded in the snapshots.
+func DiffSnapshots(oldPath, newPath string, opts Options) string {
+	oldFile, err := diffs.ReadSnapshotFile(oldPath)
+	if err != nil {
+		loggr.Fatalf("cannot read old snapshot: %v", err)
+	}
+	newFile, err := diffs.ReadSnapshotFile(newPath)
+	if err != nil {
+		loggr.Fatalf("cannot read new snapshot: %v", err)
+	}
+	if reason := oldFile.Mismatch(newFile); reason != "" {
+		loggr.Warnf("snapshots were taken differently (%s); some changes may not come from the code", reason)
+	}
+	opts.checkLoadErrors("", nil, oldFile.API, newFile.API)
+
+	var links *diffs.Links
+	if opts.LinkTemplate != "" {
+		links = &diffs.Links{Template: opts.LinkTemplate, OldRef: oldFile.Commit, NewRef: newFile.Commit}
+	}
+	return diffAPI(oldFile.API, newFile.API, opts, links)
+}
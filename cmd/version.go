@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/hashmap-kz/relimpact/internal/diffs"
+	"github.com/hashmap-kz/relimpact/internal/loggr"
+)
+
+// SuggestVersion diffs the API between oldRef (a semver tag) and newRef,
+// and suggests the next version to tag.
+func SuggestVersion(repoDir, oldRef, newRef string, opts Options) diffs.VersionSuggestion {
+	tmpOld, tmpNew := checkout(repoDir, oldRef, newRef)
+	defer cleanup(repoDir, tmpOld, tmpNew)
+
+	oldAPI, newAPI := snap(tmpOld, tmpNew, newRef, opts)
+	apiDiff := diffs.DiffAPI(diffs.FilterClass(oldAPI, diffs.ClassPublic), diffs.FilterClass(newAPI, diffs.ClassPublic))
+
+	suggestion, err := diffs.SuggestVersion(oldRef, apiDiff, diffs.ModulePath(filepath.Join(tmpNew, "go.mod")))
+	if err != nil {
+		loggr.Fatalf("cannot suggest version: %v", err)
+	}
+	return suggestion
+}
@@ -1,8 +1,6 @@
 package diffs
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
 	"go/token"
 	"go/types"
@@ -20,22 +18,97 @@ import (
 )
 
 type APIPackage struct {
-	Funcs  []string           `json:"funcs"`
-	Vars   []string           `json:"vars"`
-	Consts []string           `json:"consts"`
-	Types  map[string]APIType `json:"types"`
+	Funcs     []string                `json:"funcs"`
+	Vars      []string                `json:"vars"`
+	Consts    []string                `json:"consts"`
+	Types     map[string]APIType      `json:"types"`
+	TypeFacts map[string]APITypeFacts `json:"type_facts,omitempty"` // type used by the API -> facts, for compat rules
+
+	// Signatures maps funcs and methods ("Type.Name") to their parameter and result types, keys of TypeFacts.
+	// ValueTypes maps vars and struct fields ("Type.Name") to their type, a key of TypeFacts.
+	Signatures map[string]APISignature `json:"signatures,omitempty"`
+	ValueTypes map[string]string       `json:"value_types,omitempty"`
+
+	TypeParams map[string][]APITypeParam `json:"type_params,omitempty"` // generic func name -> type params
+
+	ConstValues map[string]string `json:"const_values,omitempty"` // const name -> exact value
+	IotaBlocks  map[string]string `json:"iota_blocks,omitempty"`  // const name -> its iota block, see iotaBlocks
+
+	// Canonical holds the entries of generic funcs, and the underlying type ("Type"), fields and methods
+	// ("Type.Name") of generic types, with type parameters named by position ($0, $1, ...). Changes are
+	// judged on them, so renaming a type parameter is not a change; the written entries are for display.
+	Canonical map[string]string `json:"canonical,omitempty"`
+
+	Decls map[string]string `json:"decls,omitempty"` // func name -> Go declaration with parameter names
+
+	Class string `json:"class,omitempty"` // public, internal, command or example
+
+	// Platforms lists the platforms the package was snapshotted on, when a platform matrix is configured.
+	// SymbolPlatforms records, for symbols missing on some of them, where they do exist ("Type.Name" for members).
+	Platforms       []string            `json:"platforms,omitempty"`
+	SymbolPlatforms map[string][]string `json:"symbol_platforms,omitempty"`
+
+	// Deprecated maps symbols ("Type.Name" for members, "" for the package) to their "Deprecated:" notice.
+	Deprecated map[string]string `json:"deprecated,omitempty"`
+
+	// Docs holds the doc comments of exported symbols, keyed like Deprecated. Only captured on request.
+	Docs map[string]string `json:"docs,omitempty"`
+
+	// Exposes maps exported symbols, keyed like Deprecated, to the third-party packages their types mention.
+	Exposes map[string][]string `json:"exposes,omitempty"`
+
+	// Positions maps exported symbols, keyed like Deprecated, to "file:line" relative to the module root.
+	Positions map[string]string `json:"positions,omitempty"`
+
+	// LoadErrors holds the errors of a package that could not be loaded; nothing else is recorded for it.
+	LoadErrors []string `json:"load_errors,omitempty"`
+
+	factTypes map[string]types.Type // the types of TypeFacts while snapshotting, see relateTypes
 }
 
 type APIType struct {
-	Kind    string   `json:"kind"`   // struct, interface, etc.
-	Fields  []string `json:"fields"` // for structs
-	Methods []string `json:"methods"`
+	Kind       string   `json:"kind"`   // kind of the underlying type: struct, interface, basic, map, etc.
+	Fields     []string `json:"fields"` // for structs
+	Methods    []string `json:"methods"`
+	Comparable bool     `json:"comparable,omitempty"`
+	Alias      bool     `json:"alias,omitempty"`
+	Underlying string   `json:"underlying,omitempty"` // underlying type, or the aliased type for aliases
+
+	Embedded []string      `json:"embedded,omitempty"` // embedded fields of structs, "Name Type"
+	Promoted []APIPromoted `json:"promoted,omitempty"` // fields and methods promoted through embedded fields
+
+	Tags map[string]string `json:"tags,omitempty"` // exported field name -> struct tag
+
+	Receivers map[string]string `json:"receivers,omitempty"` // method name -> "value" or "pointer"
+	Decls     map[string]string `json:"decls,omitempty"`     // method name -> Go declaration with parameter names
+
+	TypeParams []APITypeParam `json:"type_params,omitempty"`
+
+	Implements []string `json:"implements,omitempty"` // interfaces the type or a pointer to it implements
 }
 
 type APIDiffRes struct {
 	Label string
 	Path  string
 	X     string
+	Decl  string `json:",omitempty"` // Go declaration with parameter names, for funcs and methods
+	Pos   string `json:",omitempty"` // "file:line" relative to the module root, on the side the entry exists on
+}
+
+// APIDiffChange describes a symbol that exists on both sides under the same name,
+// but whose declaration differs (e.g. a changed signature or field type).
+type APIDiffChange struct {
+	Label string
+	Path  string
+	Name  string
+	Old   string
+	New   string
+
+	OldDecl string `json:",omitempty"`
+	NewDecl string `json:",omitempty"`
+
+	OldPos string `json:",omitempty"`
+	NewPos string `json:",omitempty"`
 }
 
 type APIDiff struct {
@@ -53,6 +126,55 @@ type APIDiff struct {
 	FieldsRemoved   []APIDiffRes `json:"fields_removed,omitempty"`
 	MethodsAdded    []APIDiffRes `json:"methods_added,omitempty"`
 	MethodsRemoved  []APIDiffRes `json:"methods_removed,omitempty"`
+
+	FuncsChanged   []APIDiffChange `json:"funcs_changed,omitempty"`
+	VarsChanged    []APIDiffChange `json:"vars_changed,omitempty"`
+	ConstsChanged  []APIDiffChange `json:"consts_changed,omitempty"`
+	FieldsChanged  []APIDiffChange `json:"fields_changed,omitempty"`
+	MethodsChanged []APIDiffChange `json:"methods_changed,omitempty"`
+
+	ReceiversChanged []APIDiffChange `json:"receivers_changed,omitempty"`
+
+	TypeParamsChanged []APIDiffChange `json:"type_params_changed,omitempty"`
+
+	TypesKindChanged       []APIDiffChange `json:"types_kind_changed,omitempty"`
+	TypesUnderlyingChanged []APIDiffChange `json:"types_underlying_changed,omitempty"`
+
+	EmbeddedAdded   []APIDiffRes `json:"embedded_added,omitempty"`
+	EmbeddedRemoved []APIDiffRes `json:"embedded_removed,omitempty"`
+	PromotedAdded   []APIDiffRes `json:"promoted_added,omitempty"`
+	PromotedRemoved []APIDiffRes `json:"promoted_removed,omitempty"`
+
+	FieldTagsChanged []APIDiffChange `json:"field_tags_changed,omitempty"`
+
+	ConstValuesChanged []APIDiffChange `json:"const_values_changed,omitempty"`
+	ConstShifts        []APIConstShift `json:"const_shifts,omitempty"`
+
+	PlatformsAdded   []APIDiffRes `json:"platforms_added,omitempty"`
+	PlatformsRemoved []APIDiffRes `json:"platforms_removed,omitempty"`
+
+	Deprecated                []APIDiffRes `json:"deprecated,omitempty"`
+	RemovedAfterDeprecation   []APIDiffRes `json:"removed_after_deprecation,omitempty"`
+	RemovedWithoutDeprecation []APIDiffRes `json:"removed_without_deprecation,omitempty"`
+
+	DocsChanged []APIDiffChange `json:"docs_changed,omitempty"`
+
+	// Compat holds every change above, classified as compatible or not.
+	Compat []APICompatChange `json:"compat,omitempty"`
+
+	ImplementsAdded   []APIDiffRes `json:"implements_added,omitempty"`
+	ImplementsRemoved []APIDiffRes `json:"implements_removed,omitempty"`
+
+	Unanalysed []APIUnanalysed `json:"unanalysed,omitempty"`
+
+	// Renames pairs removed and added symbols that look like the same symbol under a new name or package.
+	Renames []APIRename `json:"renames,omitempty"`
+
+	// GoSyntax renders funcs and methods in String() as Go declarations, with parameter names.
+	GoSyntax bool `json:"-"`
+
+	// Links renders source positions in String() as links to the old or new commit.
+	Links *Links `json:"-"`
 }
 
 func (d *APIDiff) String() string {
@@ -63,56 +185,113 @@ func (d *APIDiff) String() string {
 		Name    string
 		Added   int
 		Removed int
+		Changed int
 	}
 
 	summary := []summaryRow{
-		{"Packages", len(d.PackagesAdded), len(d.PackagesRemoved)},
-		{"Funcs", len(d.FuncsAdded), len(d.FuncsRemoved)},
-		{"Vars", len(d.VarsAdded), len(d.VarsRemoved)},
-		{"Consts", len(d.ConstsAdded), len(d.ConstsRemoved)},
-		{"Types", len(d.TypesAdded), len(d.TypesRemoved)},
-		{"Fields", len(d.FieldsAdded), len(d.FieldsRemoved)},
-		{"Methods", len(d.MethodsAdded), len(d.MethodsRemoved)},
+		{"Packages", len(d.PackagesAdded), len(d.PackagesRemoved), 0},
+		{"Funcs", len(d.FuncsAdded), len(d.FuncsRemoved), len(d.FuncsChanged)},
+		{"Vars", len(d.VarsAdded), len(d.VarsRemoved), len(d.VarsChanged)},
+		{"Consts", len(d.ConstsAdded), len(d.ConstsRemoved), len(d.ConstsChanged) + len(d.ConstValuesChanged)},
+		{"Types", len(d.TypesAdded), len(d.TypesRemoved), len(d.TypesKindChanged) + len(d.TypesUnderlyingChanged)},
+		{"Fields", len(d.FieldsAdded), len(d.FieldsRemoved), len(d.FieldsChanged)},
+		{"Methods", len(d.MethodsAdded), len(d.MethodsRemoved), len(d.MethodsChanged) + len(d.ReceiversChanged)},
+		{"Promoted", len(d.PromotedAdded), len(d.PromotedRemoved), 0},
+		{"Tags", 0, 0, len(d.FieldTagsChanged)},
+		{"Generics", 0, 0, len(d.TypeParamsChanged)},
+		{"Platform", len(d.PlatformsAdded), len(d.PlatformsRemoved), 0},
 	}
 
-	var totalAdded, totalRemoved int
+	var totalAdded, totalRemoved, totalChanged int
 	for _, s := range summary {
 		totalAdded += s.Added
 		totalRemoved += s.Removed
+		totalChanged += s.Changed
 	}
 
 	// TOC
 	sb.WriteString("\n- [Summary](#summary)\n")
 	sb.WriteString("- [Breaking Changes](#breaking-changes)\n")
+	if len(d.Unanalysed) > 0 {
+		sb.WriteString("- [Packages that could not be analysed](#packages-that-could-not-be-analysed)\n")
+	}
 	if len(d.PackagesAdded) > 0 {
 		sb.WriteString("- [Packages Added](#packages-added)\n")
 	}
 	if len(d.PackagesRemoved) > 0 {
 		sb.WriteString("- [Packages Removed](#packages-removed)\n")
 	}
+	if len(d.Renames) > 0 {
+		sb.WriteString("- [Renamed and Moved](#renamed-and-moved)\n")
+	}
+	if len(d.FuncsChanged)+len(d.VarsChanged)+len(d.ConstsChanged)+len(d.FieldsChanged)+len(d.MethodsChanged)+
+		len(d.ReceiversChanged)+len(d.TypesKindChanged)+len(d.TypesUnderlyingChanged) > 0 {
+		sb.WriteString("- [Changed Signatures](#changed-signatures)\n")
+	}
+	if len(d.TypeParamsChanged) > 0 {
+		sb.WriteString("- [Type Parameter Changes](#type-parameter-changes)\n")
+	}
+	if len(d.FieldTagsChanged) > 0 {
+		sb.WriteString("- [Field Tags Changed](#field-tags-changed)\n")
+	}
+	if len(d.ConstValuesChanged) > 0 {
+		sb.WriteString("- [Const Values Changed](#const-values-changed)\n")
+	}
+	if len(d.ImplementsAdded)+len(d.ImplementsRemoved) > 0 {
+		sb.WriteString("- [Interface Implementations](#interface-implementations)\n")
+	}
+	if len(d.Deprecated) > 0 {
+		sb.WriteString("- [Newly Deprecated](#newly-deprecated)\n")
+	}
+	if len(d.RemovedAfterDeprecation) > 0 {
+		sb.WriteString("- [Removed After Deprecation](#removed-after-deprecation)\n")
+	}
+	if len(d.RemovedWithoutDeprecation) > 0 {
+		sb.WriteString("- [Removed Without Deprecation](#removed-without-deprecation)\n")
+	}
+	if len(d.DocsChanged) > 0 {
+		sb.WriteString("- [Documentation of exported API](#documentation-of-exported-api)\n")
+	}
 	sb.WriteString("- [Package Changes](#package-changes)\n")
 
 	// Summary table
 	sb.WriteString("\n### Summary\n\n")
-	sb.WriteString("| Kind     | Added | Removed |\n")
-	sb.WriteString("|----------|------:|--------:|\n")
+	sb.WriteString("| Kind     | Added | Removed | Changed |\n")
+	sb.WriteString("|----------|------:|--------:|--------:|\n")
 	for _, s := range summary {
-		sb.WriteString(fmt.Sprintf("| %-8s | %5d | %7d |\n", s.Name, s.Added, s.Removed))
+		sb.WriteString(fmt.Sprintf("| %-8s | %5d | %7d | %7d |\n", s.Name, s.Added, s.Removed, s.Changed))
 	}
-	sb.WriteString(fmt.Sprintf("| %-8s | %5d | %7d |\n", "Total", totalAdded, totalRemoved))
+	sb.WriteString(fmt.Sprintf("| %-8s | %5d | %7d | %7d |\n", "Total", totalAdded, totalRemoved, totalChanged))
 
 	// Breaking Changes section
 	sb.WriteString("\n### Breaking Changes\n\n")
-	if totalRemoved == 0 {
+	incompatible := d.Incompatible()
+	if len(incompatible) == 0 {
 		sb.WriteString("_No breaking changes detected._\n")
 	} else {
-		for _, s := range summary {
-			if s.Removed > 0 {
-				sb.WriteString(fmt.Sprintf("- %s Removed: **%d**\n", s.Name, s.Removed))
-			}
+		for _, c := range incompatible {
+			sb.WriteString(fmt.Sprintf("- `%s` %s: %s  \n  _%s_\n", c.Path, c.Kind, d.compatSymbol(&c), c.Reason))
+		}
+	}
+
+	// Changes of existing symbols that were judged compatible deserve an explanation too
+	var compatibleChanges []APICompatChange
+	for _, c := range d.Compatible() {
+		if strings.HasPrefix(c.Kind, "Changed ") {
+			compatibleChanges = append(compatibleChanges, c)
+		}
+	}
+	if len(compatibleChanges) > 0 {
+		sb.WriteString(fmt.Sprintf("\n<details>\n<summary>Compatible changes (%d)</summary>\n\n", len(compatibleChanges)))
+		for _, c := range compatibleChanges {
+			sb.WriteString(fmt.Sprintf("- `%s` %s: %s  \n  _%s_\n", c.Path, c.Kind, d.compatSymbol(&c), c.Reason))
 		}
+		sb.WriteString("\n</details>\n")
 	}
 
+	// Packages that failed to load come right after the verdict, since it is incomplete without them
+	writeUnanalysedSection(&sb, d.Unanalysed)
+
 	// Packages added/removed
 	writeSectionSimple := func(prefix string, packages []string) {
 		if len(packages) == 0 {
@@ -129,6 +308,51 @@ func (d *APIDiff) String() string {
 	writeSectionSimple("Packages Added", d.PackagesAdded)
 	writeSectionSimple("Packages Removed", d.PackagesRemoved)
 
+	// Removals that are likely renames or moves, so release notes can point users to the new name
+	writeRenamesSection(&sb, d.Links, d.Renames)
+
+	// Changed signatures: "old → new", grouped by package
+	writeChangesSection(&sb, "Changed Signatures", d.GoSyntax, d.Links,
+		d.FuncsChanged, d.VarsChanged, d.ConstsChanged, d.FieldsChanged, d.MethodsChanged, d.ReceiversChanged,
+		d.TypesKindChanged, d.TypesUnderlyingChanged)
+
+	// Type parameters and constraints of generic funcs and types
+	writeChangesSection(&sb, "Type Parameter Changes", false, d.Links, d.TypeParamsChanged)
+
+	// Struct tags, which control the wire format of serialized types
+	writeChangesSection(&sb, "Field Tags Changed", false, d.Links, d.FieldTagsChanged)
+
+	// Constant values, with reordered iota blocks called out first
+	writeChangesSection(&sb, "Const Values Changed", false, d.Links, d.ConstValuesChanged)
+	if len(d.ConstShifts) > 0 {
+		sb.WriteString("\n")
+		for i := range d.ConstShifts {
+			sb.WriteString(fmt.Sprintf("> **Warning:** `%s`: %s\n", d.ConstShifts[i].Path, d.ConstShifts[i].String()))
+		}
+	}
+
+	// Types that stopped (or started) satisfying interfaces, which method diffs alone don't tell
+	writeImplementsSection(&sb, d.Links, d.ImplementsAdded, d.ImplementsRemoved)
+
+	// Deprecation lifecycle, from the "Deprecated:" paragraphs of doc comments
+	writeDeprecationSection(&sb, d.Links, "Newly Deprecated", d.Deprecated)
+	writeDeprecationSection(&sb, d.Links, "Removed After Deprecation", d.RemovedAfterDeprecation)
+	writeDeprecationSection(&sb, d.Links, "Removed Without Deprecation", d.RemovedWithoutDeprecation)
+
+	// Doc comments often describe behavior changes that signatures don't show
+	writeDocsSection(&sb, d.Links, "Documentation of exported API", d.DocsChanged)
+
+	grouped := d.packageChanges()
+	if len(grouped) > 0 {
+		sb.WriteString("\n### Package Changes\n")
+		writeGrouped(&sb, grouped)
+	}
+
+	return sb.String()
+}
+
+// packageChanges groups added and removed entries as package -> "Added/Removed <label>" -> items.
+func (d *APIDiff) packageChanges() map[string]map[string][]string {
 	type changeKind string
 	const (
 		added   changeKind = "Added"
@@ -137,12 +361,13 @@ func (d *APIDiff) String() string {
 
 	groupByPkgLabel := func(items []APIDiffRes, kind changeKind) map[string]map[string][]string {
 		group := make(map[string]map[string][]string)
-		for _, res := range items {
+		for i := range items {
+			res := &items[i]
 			if _, ok := group[res.Path]; !ok {
 				group[res.Path] = make(map[string][]string)
 			}
 			key := fmt.Sprintf("%s %s", kind, res.Label)
-			group[res.Path][key] = append(group[res.Path][key], res.X)
+			group[res.Path][key] = append(group[res.Path][key], d.Links.withPosition(res.display(d.GoSyntax), res.Pos, kind == removed))
 		}
 		return group
 	}
@@ -171,66 +396,187 @@ func (d *APIDiff) String() string {
 	mergeGroup(groupByPkgLabel(d.FieldsRemoved, removed))
 	mergeGroup(groupByPkgLabel(d.MethodsAdded, added))
 	mergeGroup(groupByPkgLabel(d.MethodsRemoved, removed))
+	mergeGroup(groupByPkgLabel(d.EmbeddedAdded, added))
+	mergeGroup(groupByPkgLabel(d.EmbeddedRemoved, removed))
+	mergeGroup(groupByPkgLabel(d.PromotedAdded, added))
+	mergeGroup(groupByPkgLabel(d.PromotedRemoved, removed))
+	mergeGroup(groupByPkgLabel(d.PlatformsAdded, added))
+	mergeGroup(groupByPkgLabel(d.PlatformsRemoved, removed))
+
+	return grouped
+}
 
+// writeChangesSection renders changed entries as "old → new" under a heading, grouped by package.
+func writeChangesSection(sb *strings.Builder, heading string, goSyntax bool, links *Links, lists ...[]APIDiffChange) {
+	grouped := groupChanges(goSyntax, links, lists...)
 	if len(grouped) > 0 {
-		sb.WriteString("\n### Package Changes\n")
-		pkgs := make([]string, 0, len(grouped))
-		for pkg := range grouped {
-			pkgs = append(pkgs, pkg)
-		}
-		sort.Strings(pkgs)
-
-		for _, pkg := range pkgs {
-			sb.WriteString(fmt.Sprintf("\n#### Package `%s`\n\n", pkg))
-			sb.WriteString("<details>\n<summary>Click to expand</summary>\n\n")
+		sb.WriteString(fmt.Sprintf("\n### %s\n", heading))
+		writeGrouped(sb, grouped)
+	}
+}
 
-			labels := make([]string, 0, len(grouped[pkg]))
-			for label := range grouped[pkg] {
-				labels = append(labels, label)
+// groupChanges groups changed entries as package -> "Changed <label>" -> "`old` → `new`".
+func groupChanges(goSyntax bool, links *Links, lists ...[]APIDiffChange) map[string]map[string][]string {
+	grouped := make(map[string]map[string][]string)
+	for _, items := range lists {
+		for i := range items {
+			c := &items[i]
+			if _, ok := grouped[c.Path]; !ok {
+				grouped[c.Path] = make(map[string][]string)
 			}
-			sort.Strings(labels)
-
-			for _, label := range labels {
-				sb.WriteString(fmt.Sprintf("- %s:\n", label))
-				xs := grouped[pkg][label]
-				sort.Strings(xs)
-				for _, x := range xs {
-					sb.WriteString(fmt.Sprintf("    - %s\n", x))
-				}
+			key := "Changed " + c.Label
+			oldX, newX := c.display(goSyntax)
+			text := fmt.Sprintf("`%s` → `%s`", oldX, newX)
+			if c.NewPos != "" {
+				text = links.withPosition(text, c.NewPos, false)
+			} else {
+				text = links.withPosition(text, c.OldPos, true)
 			}
-
-			sb.WriteString("\n</details>\n")
+			grouped[c.Path][key] = append(grouped[c.Path][key], text)
 		}
 	}
+	return grouped
+}
 
-	return sb.String()
+// writeGrouped renders package -> label -> items as collapsible per-package lists.
+func writeGrouped(sb *strings.Builder, grouped map[string]map[string][]string) {
+	pkgs := make([]string, 0, len(grouped))
+	for pkg := range grouped {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	for _, pkg := range pkgs {
+		sb.WriteString(fmt.Sprintf("\n#### Package `%s`\n\n", pkg))
+		sb.WriteString("<details>\n<summary>Click to expand</summary>\n\n")
+
+		labels := make([]string, 0, len(grouped[pkg]))
+		for label := range grouped[pkg] {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+
+		for _, label := range labels {
+			sb.WriteString(fmt.Sprintf("- %s:\n", label))
+			xs := grouped[pkg][label]
+			sort.Strings(xs)
+			for _, x := range xs {
+				sb.WriteString(fmt.Sprintf("    - %s\n", x))
+			}
+		}
+
+		sb.WriteString("\n</details>\n")
+	}
 }
 
-func getCacheDir() string {
+// CacheDir is where API snapshots are cached: $RELIMPACT_API_CACHE_DIR, or relimpact-api-cache in the temp dir.
+func CacheDir() string {
 	if dir := os.Getenv("RELIMPACT_API_CACHE_DIR"); dir != "" {
 		return dir
 	}
 	return filepath.Join(os.TempDir(), "relimpact-api-cache") // fallback for local runs
 }
 
+// SnapshotOptions configure how an API snapshot is taken.
+type SnapshotOptions struct {
+	// Platforms to load packages under. Their APIs are merged, and symbols that exist
+	// on only some of them are annotated with where they do. Empty means the host platform.
+	Platforms []Platform
+
+	// Docs captures the doc comment of every exported symbol.
+	Docs bool
+
+	// Interfaces outside the module that types are checked against, besides the module's own
+	// exported interfaces. Nil means DefaultInterfaces.
+	Interfaces []WellKnownInterface
+
+	// Notes reads snapshots from, and writes them to, git notes under NotesRef, so they can be
+	// pushed and fetched with the repository. The local cache is still checked first.
+	Notes bool
+
+	// NoCache always loads the packages, bypassing the cache and notes. For a working tree with
+	// uncommitted changes, whose content is not that of its HEAD commit.
+	NoCache bool
+}
+
 func SnapshotAPI(dir string) map[string]APIPackage {
+	return SnapshotAPIWith(dir, SnapshotOptions{})
+}
+
+func SnapshotAPIWith(dir string, opts SnapshotOptions) map[string]APIPackage {
 	// TODO: debuglog
 
 	sha := getGitCommitSHA(dir)
-	cachePath := filepath.Join(getCacheDir(), sha+".json")
+	if opts.NoCache {
+		return takeSnapshot(dir, sha+"+dirty", &opts)
+	}
+	variant := snapshotVariant(dir, &opts)
+	cacheKey := sha
+	if variant != "" {
+		cacheKey += "-" + variant
+	}
+	cachePath := filepath.Join(CacheDir(), cacheKey+".json")
 	loggr.Debugf("cache path: %s", cachePath)
 
-	// Try to load from cache
-	if data, err := os.ReadFile(cachePath); err == nil {
-		var cached map[string]APIPackage
-		if json.Unmarshal(data, &cached) == nil {
-			loggr.Debugf("cache hit. sha=%s", sha)
-			return cached
+	header := newCacheHeader(dir, &opts)
+	fromNote := false
+	api := cachedSnapshot(cachePath, &header, func() map[string]APIPackage {
+		if opts.Notes {
+			if api, ok := readNote(dir, sha, variant, &header); ok {
+				loggr.Debugf("notes hit. sha=%s, variant=%q", sha, variant)
+				fromNote = true
+				return api
+			}
+		}
+		return takeSnapshot(dir, sha, &opts)
+	})
+	// a local cache hit still publishes the snapshot, e.g. when notes are enabled after the first run
+	if opts.Notes && !fromNote {
+		if _, ok := readNote(dir, sha, variant, &header); !ok {
+			if err := writeNote(dir, sha, variant, header, api); err != nil {
+				loggr.Warnf("cannot store snapshot in %s: %v", NotesRef, err)
+			}
 		}
 	}
+	return api
+}
 
-	loggr.Debugf("cache miss. sha=%s", sha)
+// snapshotVariant keys what, besides the commit, a snapshot depends on: the module within the
+// repository and the snapshot options. Empty for the root module with default options.
+func snapshotVariant(dir string, opts *SnapshotOptions) string {
+	var parts []string
+	if prefix := getGitPrefix(dir); prefix != "" {
+		// nested module of a multi-module repository
+		parts = append(parts, shortHash(prefix))
+	}
+	if len(opts.Platforms) > 0 {
+		parts = append(parts, platformsKey(opts.Platforms))
+	}
+	if opts.Docs {
+		parts = append(parts, "docs")
+	}
+	if opts.Interfaces != nil {
+		parts = append(parts, interfacesKey(opts.Interfaces))
+	}
+	return strings.Join(parts, "-")
+}
+
+// takeSnapshot loads and snapshots the packages of dir, on every platform of opts.
+func takeSnapshot(dir, sha string, opts *SnapshotOptions) map[string]APIPackage {
+	modulePath := getModulePath(dir)
+	if len(opts.Platforms) == 0 {
+		return snapshotPackages(loadPackages(dir, sha, nil, nil), modulePath, opts)
+	}
+	snaps := make([]map[string]APIPackage, 0, len(opts.Platforms))
+	for _, p := range opts.Platforms {
+		loggr.Debugf("snapshot platform. platform=%s, sha=%s", p.String(), sha)
+		snaps = append(snaps, snapshotPackages(loadPackages(dir, sha, p.Env(), p.BuildFlags()), modulePath, opts))
+	}
+	return mergePlatforms(snaps, opts.Platforms)
+}
 
+// loadPackages loads every package under dir, with extra environment and build flags selecting a platform.
+func loadPackages(dir, sha string, env, buildFlags []string) []*packages.Package {
 	//nolint:gocritic
 	// cfg := &packages.Config{
 	// 	Mode: packages.NeedName |
@@ -242,8 +588,12 @@ func SnapshotAPI(dir string) map[string]APIPackage {
 	// }
 
 	cfg := &packages.Config{
-		Mode: packages.NeedName | packages.NeedTypes | packages.NeedImports,
-		Dir:  dir,
+		Mode:       packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedSyntax | packages.NeedModule,
+		Dir:        dir,
+		BuildFlags: buildFlags,
+	}
+	if len(env) > 0 {
+		cfg.Env = append(os.Environ(), env...)
 	}
 
 	// NOTE: this is the most expensive routine in the whole app.
@@ -256,28 +606,60 @@ func SnapshotAPI(dir string) map[string]APIPackage {
 	}
 
 	loggr.Debugf("packages load. time=%s, sha=%s", time.Since(loadStart).String(), sha)
+	return pkgs
+}
 
-	modulePath := getModulePath(dir)
+// snapshotPackages records the exported API of the loaded packages that belong to the module.
+func snapshotPackages(pkgs []*packages.Package, modulePath string, opts *SnapshotOptions) map[string]APIPackage {
 	api := make(map[string]APIPackage)
 
+	var modulePkgs []*packages.Package
 	for _, pkg := range pkgs {
+		if !strings.HasPrefix(pkg.PkgPath, modulePath) {
+			continue
+		}
+		// in a workspace, ./... also matches packages of nested modules
+		if pkg.Module != nil && pkg.Module.Path != modulePath {
+			continue
+		}
+
+		// keep failed packages with their errors, so they are not mistaken for removed ones
 		if len(pkg.Errors) > 0 {
 			loggr.Errorf("error in package: %s", pkg.PkgPath)
+			apkg := APIPackage{Class: classifyPackage(pkg.PkgPath, modulePath, pkg.Name)}
 			for _, err := range pkg.Errors {
 				loggr.Errorf("error details: %v", err)
+				apkg.LoadErrors = append(apkg.LoadErrors, loadError(err, pkg.Module))
 			}
+			api[pkg.PkgPath] = apkg
 			continue
 		}
+		modulePkgs = append(modulePkgs, pkg)
+	}
 
-		if !strings.HasPrefix(pkg.PkgPath, modulePath) {
-			continue
-		}
+	ifaces := moduleInterfaces(modulePkgs)
+	wellKnown := opts.Interfaces
+	if wellKnown == nil {
+		wellKnown = DefaultInterfaces
+	}
+
+	for _, pkg := range modulePkgs {
 
 		apkg := APIPackage{
-			Funcs:  []string{},
-			Vars:   []string{},
-			Consts: []string{},
-			Types:  make(map[string]APIType),
+			Class:       classifyPackage(pkg.PkgPath, modulePath, pkg.Name),
+			Funcs:       []string{},
+			Vars:        []string{},
+			Consts:      []string{},
+			Types:       make(map[string]APIType),
+			TypeFacts:   make(map[string]APITypeFacts),
+			TypeParams:  make(map[string][]APITypeParam),
+			ConstValues: make(map[string]string),
+			Decls:       make(map[string]string),
+			Deprecated:  deprecationNotices(pkg.Syntax),
+			IotaBlocks:  iotaBlocks(pkg.Syntax),
+		}
+		if opts.Docs {
+			apkg.Docs = symbolDocs(pkg.Syntax)
 		}
 
 		scope := pkg.Types.Scope()
@@ -293,158 +675,314 @@ func SnapshotAPI(dir string) map[string]APIPackage {
 					//nolint:errcheck
 					sig := o.Type().(*types.Signature)
 					apkg.Funcs = append(apkg.Funcs, name+signatureString(sig))
+					apkg.Decls[name] = goDecl(o, pkg.Types)
+					apkg.recordSignature(name, sig)
+					if tps := typeParamList(sig.TypeParams()); tps != nil {
+						apkg.TypeParams[name] = tps
+						apkg.recordGenericFunc(name, sig)
+					}
 				}
 			case *types.Var:
 				if o.IsField() {
 					continue
 				}
 				apkg.Vars = append(apkg.Vars, name+" "+o.Type().String())
+				apkg.recordValue(name, o.Type())
 			case *types.Const:
 				apkg.Consts = append(apkg.Consts, name+" "+o.Type().String())
+				apkg.ConstValues[name] = o.Val().ExactString()
 			case *types.TypeName:
-				t := o.Type().Underlying()
-				atype := APIType{}
-				switch ut := t.(type) {
-				case *types.Struct:
-					atype.Kind = "struct"
-					for i := 0; i < ut.NumFields(); i++ {
-						f := ut.Field(i)
-						if f.Exported() {
-							atype.Fields = append(atype.Fields, f.Name()+" "+f.Type().String())
-						}
-					}
-				case *types.Interface:
-					atype.Kind = "interface"
-					for i := 0; i < ut.NumMethods(); i++ {
-						m := ut.Method(i)
-						//nolint:errcheck
-						atype.Methods = append(atype.Methods, m.Name()+signatureString(m.Type().(*types.Signature)))
-					}
-				default:
-					atype.Kind = fmt.Sprintf("%T", ut)
-				}
-
-				methodSet := types.NewMethodSet(o.Type())
-				for i := 0; i < methodSet.Len(); i++ {
-					m := methodSet.At(i)
-					if m.Obj().Exported() {
-						//nolint:errcheck
-						atype.Methods = append(atype.Methods, m.Obj().Name()+signatureString(m.Obj().Type().(*types.Signature)))
-					}
-				}
-
+				atype := snapshotType(o, &apkg)
+				atype.Implements = implementedInterfaces(o, &atype, ifaces, wellKnown)
 				apkg.Types[name] = atype
 			}
 		}
 
+		apkg.relateTypes()
+		apkg.Exposes = exposedPackages(pkg.Types, modulePath)
+		if pkg.Module != nil && pkg.Module.Dir != "" {
+			apkg.Positions = symbolPositions(pkg.Types, pkg.Fset, pkg.Module.Dir)
+		}
 		api[pkg.PkgPath] = apkg
 	}
 
-	// TODO: checksum
+	return api
+}
 
-	// Save to cache
-	if err := os.MkdirAll(filepath.Dir(cachePath), 0o750); err == nil {
-		if data, err := json.MarshalIndent(api, "", "  "); err == nil {
-			//nolint:errcheck
-			_ = os.WriteFile(cachePath, data, 0o600)
+// snapshotType records the exported shape of a named type or alias.
+func snapshotType(o *types.TypeName, apkg *APIPackage) APIType {
+	atype := APIType{
+		Comparable: types.Comparable(o.Type()),
+		Alias:      o.IsAlias(),
+		Kind:       typeKind(o.Type()),
+	}
+	if o.IsAlias() {
+		atype.Underlying = types.Unalias(o.Type()).String()
+	} else {
+		atype.Underlying = o.Type().Underlying().String()
+	}
+	if named, ok := o.Type().(*types.Named); ok {
+		atype.TypeParams = typeParamList(named.TypeParams())
+		if atype.TypeParams != nil {
+			apkg.recordGenericType(o.Name(), named)
 		}
 	}
+	if !o.IsAlias() {
+		atype.Embedded, atype.Promoted = promotedMembers(o.Type(), o.Pkg())
+	}
 
-	return api
-}
-
-func signatureString(sig *types.Signature) string {
-	var b bytes.Buffer
-	b.WriteString("(")
-	for i := 0; i < sig.Params().Len(); i++ {
-		if i > 0 {
-			b.WriteString(", ")
-		}
-		b.WriteString(sig.Params().At(i).Type().String())
-	}
-	b.WriteString(")")
-	if sig.Results().Len() > 0 {
-		b.WriteString(" -> (")
-		for i := 0; i < sig.Results().Len(); i++ {
-			if i > 0 {
-				b.WriteString(", ")
+	switch ut := o.Type().Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < ut.NumFields(); i++ {
+			f := ut.Field(i)
+			if f.Exported() {
+				atype.Fields = append(atype.Fields, f.Name()+" "+f.Type().String())
+				apkg.recordValue(o.Name()+"."+f.Name(), f.Type())
+				if tag := ut.Tag(i); tag != "" {
+					if atype.Tags == nil {
+						atype.Tags = make(map[string]string)
+					}
+					atype.Tags[f.Name()] = tag
+				}
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < ut.NumMethods(); i++ {
+			m := ut.Method(i)
+			//nolint:errcheck
+			sig := m.Type().(*types.Signature)
+			atype.Methods = append(atype.Methods, m.Name()+signatureString(sig))
+			atype.Decls = setDecl(atype.Decls, m.Name(), goDecl(m, o.Pkg()))
+			if m.Exported() {
+				apkg.recordSignature(o.Name()+"."+m.Name(), sig)
 			}
-			b.WriteString(sig.Results().At(i).Type().String())
 		}
-		b.WriteString(")")
+		return atype
 	}
-	return b.String()
+
+	// methods of both T and *T, with the receiver each one needs
+	methods, receivers := methodSets(o.Type())
+	for _, m := range methods {
+		//nolint:errcheck
+		sig := m.Type().(*types.Signature)
+		atype.Methods = append(atype.Methods, m.Name()+signatureString(sig))
+		atype.Decls = setDecl(atype.Decls, m.Name(), goDecl(m, o.Pkg()))
+		apkg.recordSignature(o.Name()+"."+m.Name(), sig)
+	}
+	atype.Receivers = receivers
+
+	return atype
 }
 
 func DiffAPI(oldAPI, newAPI map[string]APIPackage) *APIDiff {
 	apiDiffResult := &APIDiff{}
 
+	// packages that failed to load on either side are listed, but not compared
+	apiDiffResult.Unanalysed = unanalysedPackages(oldAPI, newAPI)
+	oldAPI = withoutPackages(oldAPI, apiDiffResult.Unanalysed)
+	newAPI = withoutPackages(newAPI, apiDiffResult.Unanalysed)
+	var compat []APICompatChange
+	oldMatrix, newMatrix := snapshotMatrix(oldAPI), snapshotMatrix(newAPI)
+
+	// a renamed type is reported once, not again in every signature that mentions it
+	apiDiffResult.Renames = DetectRenames(oldAPI, newAPI)
+	renameTypes := typeRenamer(apiDiffResult.Renames)
+
 	for path, newPkg := range newAPI {
 		oldPkg, ok := oldAPI[path]
 
 		// packages +
 		if !ok {
 			apiDiffResult.PackagesAdded = append(apiDiffResult.PackagesAdded, path)
+			compat = append(compat, APICompatChange{Path: path, Kind: "Added Packages", Symbol: path, Compatible: true, Reason: "new package"})
 			continue
 		}
 
 		// Funcs
-		funcsAdd, funcsRem := diffList("Funcs", path, oldPkg.Funcs, newPkg.Funcs)
-		apiDiffResult.FuncsAdded = append(apiDiffResult.FuncsAdded, funcsAdd...)
-		apiDiffResult.FuncsRemoved = append(apiDiffResult.FuncsRemoved, funcsRem...)
+		funcsAdd, funcsRem, funcsChanged := diffNamedList("Funcs", path, oldPkg.Funcs, newPkg.Funcs)
+		funcsChanged = withoutTypeParamRenames(funcsChanged, &oldPkg, &newPkg, "")
+		funcsChanged = withoutTypeRenames(funcsChanged, renameTypes)
+		apiDiffResult.FuncsAdded = append(apiDiffResult.FuncsAdded, withPositions(withDecls(funcsAdd, newPkg.Decls), newPkg.Positions, "")...)
+		apiDiffResult.FuncsRemoved = append(apiDiffResult.FuncsRemoved, withPositions(withDecls(funcsRem, oldPkg.Decls), oldPkg.Positions, "")...)
+		apiDiffResult.FuncsChanged = append(apiDiffResult.FuncsChanged, withChangePositions(withChangeDecls(funcsChanged, oldPkg.Decls, newPkg.Decls), oldPkg.Positions, newPkg.Positions, "")...)
+		compat = append(compat, compatRemoved("Funcs", funcsRem, "callers of the function no longer compile")...)
+		compat = append(compat, compatAdded("Funcs", funcsAdd, "new function")...)
+		compat = append(compat, compatSignatures("Funcs", "", funcsChanged, &oldPkg, &newPkg)...)
+
+		// Funcs type params
+		funcTPsChanged := diffTypeParams("Generic Funcs", path, oldPkg.TypeParams, newPkg.TypeParams, func(name string) bool {
+			return containsName(oldPkg.Funcs, name) && containsName(newPkg.Funcs, name)
+		})
+		funcTPsChanged = withChangePositions(funcTPsChanged, oldPkg.Positions, newPkg.Positions, "")
+		apiDiffResult.TypeParamsChanged = append(apiDiffResult.TypeParamsChanged, funcTPsChanged...)
+		compat = append(compat, compatTypeParams(oldPkg.TypeParams, newPkg.TypeParams, funcTPsChanged)...)
 
 		// Vars
-		varsAdded, varsRemoved := diffList("Vars", path, oldPkg.Vars, newPkg.Vars)
-		apiDiffResult.VarsAdded = append(apiDiffResult.VarsAdded, varsAdded...)
-		apiDiffResult.VarsRemoved = append(apiDiffResult.VarsRemoved, varsRemoved...)
+		varsAdded, varsRemoved, varsChanged := diffNamedList("Vars", path, oldPkg.Vars, newPkg.Vars)
+		varsChanged = withoutTypeRenames(varsChanged, renameTypes)
+		apiDiffResult.VarsAdded = append(apiDiffResult.VarsAdded, withPositions(varsAdded, newPkg.Positions, "")...)
+		apiDiffResult.VarsRemoved = append(apiDiffResult.VarsRemoved, withPositions(varsRemoved, oldPkg.Positions, "")...)
+		apiDiffResult.VarsChanged = append(apiDiffResult.VarsChanged, withChangePositions(varsChanged, oldPkg.Positions, newPkg.Positions, "")...)
+		compat = append(compat, compatRemoved("Vars", varsRemoved, "references to the variable no longer compile")...)
+		compat = append(compat, compatAdded("Vars", varsAdded, "new variable")...)
+		compat = append(compat, compatValues("Vars", "", varsChanged, &oldPkg, &newPkg, "variable type changed; uses of its value may no longer compile")...)
 
 		// Consts
-		constsAdded, constsRemoved := diffList("Consts", path, oldPkg.Consts, newPkg.Consts)
-		apiDiffResult.ConstsAdded = append(apiDiffResult.ConstsAdded, constsAdded...)
-		apiDiffResult.ConstsRemoved = append(apiDiffResult.ConstsRemoved, constsRemoved...)
+		constsAdded, constsRemoved, constsChanged := diffNamedList("Consts", path, oldPkg.Consts, newPkg.Consts)
+		apiDiffResult.ConstsAdded = append(apiDiffResult.ConstsAdded, withPositions(constsAdded, newPkg.Positions, "")...)
+		apiDiffResult.ConstsRemoved = append(apiDiffResult.ConstsRemoved, withPositions(constsRemoved, oldPkg.Positions, "")...)
+		apiDiffResult.ConstsChanged = append(apiDiffResult.ConstsChanged, withChangePositions(constsChanged, oldPkg.Positions, newPkg.Positions, "")...)
+		compat = append(compat, compatRemoved("Consts", constsRemoved, "references to the constant no longer compile")...)
+		compat = append(compat, compatAdded("Consts", constsAdded, "new constant")...)
+		compat = append(compat, compatIncompatible("Consts", constsChanged, "constant type changed; typed uses may no longer compile")...)
+
+		// Const values
+		constValuesChanged := withChangePositions(diffConstValues(path, &oldPkg, &newPkg), oldPkg.Positions, newPkg.Positions, "")
+		constShifts := detectConstShifts(path, &newPkg, constValuesChanged)
+		apiDiffResult.ConstValuesChanged = append(apiDiffResult.ConstValuesChanged, constValuesChanged...)
+		apiDiffResult.ConstShifts = append(apiDiffResult.ConstShifts, constShifts...)
+		compat = append(compat, compatConstValues(constValuesChanged, constShifts)...)
 
 		// Types
 		for tname, newType := range newPkg.Types {
 			oldType, ok := oldPkg.Types[tname]
 			if !ok {
 				// types +
-				apiDiffResult.TypesAdded = append(apiDiffResult.TypesAdded, APIDiffRes{
+				typeAdded := APIDiffRes{
 					Label: "Type",
 					Path:  path,
 					X:     tname,
-				})
+					Pos:   newPkg.Positions[tname],
+				}
+				apiDiffResult.TypesAdded = append(apiDiffResult.TypesAdded, typeAdded)
+				compat = append(compat, compatAdded("Types", []APIDiffRes{typeAdded}, "new type")...)
 				continue
 			}
 
+			// kind, alias-ness and underlying type
+			kindChanged, underlyingChanged := diffTypeForm(path, tname, &oldType, &newType)
+			underlyingChanged = withoutTypeParamRenames(underlyingChanged, &oldPkg, &newPkg, "")
+			underlyingChanged = withoutTypeRenames(underlyingChanged, renameTypes)
+			kindChanged = withChangePositions(kindChanged, oldPkg.Positions, newPkg.Positions, "")
+			underlyingChanged = withChangePositions(underlyingChanged, oldPkg.Positions, newPkg.Positions, "")
+			apiDiffResult.TypesKindChanged = append(apiDiffResult.TypesKindChanged, kindChanged...)
+			apiDiffResult.TypesUnderlyingChanged = append(apiDiffResult.TypesUnderlyingChanged, underlyingChanged...)
+			compat = append(compat, compatIncompatible("Type Kind", kindChanged, "type kind changed; conversions, literals and operations on it break")...)
+			compat = append(compat, compatIncompatible("Underlying Type", underlyingChanged, "underlying type changed; conversions, literals and operations on it break")...)
+
 			// fields
-			fieldsAdded, fieldsRemoved := diffList(fmt.Sprintf("Type `%s` Fields", tname), path, oldType.Fields, newType.Fields)
-			apiDiffResult.FieldsAdded = append(apiDiffResult.FieldsAdded, fieldsAdded...)
-			apiDiffResult.FieldsRemoved = append(apiDiffResult.FieldsRemoved, fieldsRemoved...)
+			fieldsLabel := fmt.Sprintf("Type `%s` Fields", tname)
+			fieldsAdded, fieldsRemoved, fieldsChanged := diffNamedList(fieldsLabel, path, oldType.Fields, newType.Fields)
+			fieldsChanged = withoutTypeParamRenames(fieldsChanged, &oldPkg, &newPkg, tname)
+			fieldsChanged = withoutTypeRenames(fieldsChanged, renameTypes)
+			apiDiffResult.FieldsAdded = append(apiDiffResult.FieldsAdded, withPositions(fieldsAdded, newPkg.Positions, tname)...)
+			apiDiffResult.FieldsRemoved = append(apiDiffResult.FieldsRemoved, withPositions(fieldsRemoved, oldPkg.Positions, tname)...)
+			apiDiffResult.FieldsChanged = append(apiDiffResult.FieldsChanged, withChangePositions(fieldsChanged, oldPkg.Positions, newPkg.Positions, tname)...)
+			compat = append(compat, compatRemoved(fieldsLabel, fieldsRemoved, "selectors and keyed literals using the field no longer compile")...)
+			compat = append(compat, compatAdded(fieldsLabel, fieldsAdded, "new field")...)
+			compat = append(compat, compatValues(fieldsLabel, tname, fieldsChanged, &oldPkg, &newPkg, "field type changed; uses of the field may no longer compile")...)
+
+			// struct tags
+			tagsChanged := diffTags(fmt.Sprintf("Type `%s` Field Tags", tname), path, oldType.Tags, newType.Tags, oldType.Fields, newType.Fields)
+			tagsChanged = withChangePositions(tagsChanged, oldPkg.Positions, newPkg.Positions, tname)
+			apiDiffResult.FieldTagsChanged = append(apiDiffResult.FieldTagsChanged, tagsChanged...)
+			compat = append(compat, compatTags(oldType.Tags, newType.Tags, tagsChanged)...)
 
 			// methods
-			methodsAdded, methodsRemoved := diffList(fmt.Sprintf("Type `%s` Methods", tname), path, oldType.Methods, newType.Methods)
-			apiDiffResult.MethodsAdded = append(apiDiffResult.MethodsAdded, methodsAdded...)
-			apiDiffResult.MethodsRemoved = append(apiDiffResult.MethodsRemoved, methodsRemoved...)
+			methodsAdded, methodsRemoved, methodsChanged := diffNamedList(fmt.Sprintf("Type `%s` Methods", tname), path, oldType.Methods, newType.Methods)
+			methodsChanged = withoutTypeParamRenames(methodsChanged, &oldPkg, &newPkg, tname)
+			methodsChanged = withoutTypeRenames(methodsChanged, renameTypes)
+			apiDiffResult.MethodsAdded = append(apiDiffResult.MethodsAdded, withPositions(withDecls(methodsAdded, newType.Decls), newPkg.Positions, tname)...)
+			apiDiffResult.MethodsRemoved = append(apiDiffResult.MethodsRemoved, withPositions(withDecls(methodsRemoved, oldType.Decls), oldPkg.Positions, tname)...)
+			apiDiffResult.MethodsChanged = append(apiDiffResult.MethodsChanged, withChangePositions(withChangeDecls(methodsChanged, oldType.Decls, newType.Decls), oldPkg.Positions, newPkg.Positions, tname)...)
+			receiversChanged := withChangePositions(diffReceivers(path, tname, &oldType, &newType), oldPkg.Positions, newPkg.Positions, tname)
+			apiDiffResult.ReceiversChanged = append(apiDiffResult.ReceiversChanged, receiversChanged...)
+			compat = append(compat, compatReceivers(tname, receiversChanged)...)
+
+			// interfaces the type satisfies
+			implementsAdded, implementsRemoved := diffImplements(path, tname, &oldType, &newType)
+			implementsAdded = withTypePositions(implementsAdded, newPkg.Positions, tname)
+			implementsRemoved = withTypePositions(implementsRemoved, newPkg.Positions, tname)
+			apiDiffResult.ImplementsAdded = append(apiDiffResult.ImplementsAdded, implementsAdded...)
+			apiDiffResult.ImplementsRemoved = append(apiDiffResult.ImplementsRemoved, implementsRemoved...)
+			compat = append(compat, compatRemoved(fmt.Sprintf("Type `%s` Implements", tname), implementsRemoved, "values of the type can no longer be used as this interface")...)
+			compat = append(compat, compatAdded(fmt.Sprintf("Type `%s` Implements", tname), implementsAdded, "type now satisfies the interface")...)
+
+			// embedded fields and the members they promote
+			embeddedAdded, embeddedRemoved := diffList(fmt.Sprintf("Type `%s` Embedded", tname), path, oldType.Embedded, newType.Embedded)
+			apiDiffResult.EmbeddedAdded = append(apiDiffResult.EmbeddedAdded, withTypePositions(embeddedAdded, newPkg.Positions, tname)...)
+			apiDiffResult.EmbeddedRemoved = append(apiDiffResult.EmbeddedRemoved, withTypePositions(embeddedRemoved, oldPkg.Positions, tname)...)
+			promotedLabel := fmt.Sprintf("Type `%s` Promoted", tname)
+			promotedAdded, promotedRemoved := diffPromoted(promotedLabel, path, oldType.Promoted, newType.Promoted)
+			promotedAdded = withTypePositions(promotedAdded, newPkg.Positions, tname)
+			promotedRemoved = withTypePositions(promotedRemoved, oldPkg.Positions, tname)
+			apiDiffResult.PromotedAdded = append(apiDiffResult.PromotedAdded, promotedAdded...)
+			apiDiffResult.PromotedRemoved = append(apiDiffResult.PromotedRemoved, promotedRemoved...)
+			compat = append(compat, compatRemoved(promotedLabel, promotedRemoved, "promoted member is gone, because the embedding it came through was removed or changed")...)
+			compat = append(compat, compatAdded(promotedLabel, promotedAdded, "new promoted member")...)
+
+			// promoted method removals are already classified above, along with the embedding that caused them
+			compat = append(compat, compatType(path, tname, &oldType, &newType, &oldPkg, &newPkg,
+				methodsAdded, withoutPromoted(methodsRemoved, oldType.Promoted), methodsChanged)...)
+
+			// type params
+			oldTPs := map[string][]APITypeParam{tname: oldType.TypeParams}
+			newTPs := map[string][]APITypeParam{tname: newType.TypeParams}
+			typeTPsChanged := diffTypeParams("Generic Types", path, oldTPs, newTPs, func(string) bool { return true })
+			typeTPsChanged = withChangePositions(typeTPsChanged, oldPkg.Positions, newPkg.Positions, "")
+			apiDiffResult.TypeParamsChanged = append(apiDiffResult.TypeParamsChanged, typeTPsChanged...)
+			compat = append(compat, compatTypeParams(oldTPs, newTPs, typeTPsChanged)...)
 		}
 		// types -
 		for tname := range oldPkg.Types {
 			if _, ok := newPkg.Types[tname]; !ok {
-				apiDiffResult.TypesRemoved = append(apiDiffResult.TypesRemoved, APIDiffRes{
+				typeRemoved := APIDiffRes{
 					Label: "Type",
 					Path:  path,
 					X:     tname,
-				})
+					Pos:   oldPkg.Positions[tname],
+				}
+				apiDiffResult.TypesRemoved = append(apiDiffResult.TypesRemoved, typeRemoved)
+				compat = append(compat, compatRemoved("Types", []APIDiffRes{typeRemoved}, "references to the type no longer compile")...)
 			}
 		}
+
+		// symbols that exist on fewer (or more) platforms than before
+		platformsAdded, platformsRemoved := diffPlatforms(path, &oldPkg, &newPkg, oldMatrix, newMatrix)
+		apiDiffResult.PlatformsAdded = append(apiDiffResult.PlatformsAdded, platformsAdded...)
+		apiDiffResult.PlatformsRemoved = append(apiDiffResult.PlatformsRemoved, platformsRemoved...)
+		compat = append(compat, compatRemoved("Platforms", platformsRemoved, "platform-specific break: code built for these platforms no longer compiles")...)
+		compat = append(compat, compatAdded("Platforms", platformsAdded, "now available on more platforms")...)
+
+		// deprecations
+		deprecated, removedAfter, removedWithout := diffDeprecations(path, &oldPkg, &newPkg)
+		apiDiffResult.Deprecated = append(apiDiffResult.Deprecated, deprecated...)
+		apiDiffResult.RemovedAfterDeprecation = append(apiDiffResult.RemovedAfterDeprecation, removedAfter...)
+		apiDiffResult.RemovedWithoutDeprecation = append(apiDiffResult.RemovedWithoutDeprecation, removedWithout...)
+
+		// doc comments
+		apiDiffResult.DocsChanged = append(apiDiffResult.DocsChanged, withChangePositions(diffSymbolDocs(path, &oldPkg, &newPkg), oldPkg.Positions, newPkg.Positions, "")...)
 	}
 
 	// packages -
-	for path := range oldAPI {
+	for path, oldPkg := range oldAPI {
 		if _, ok := newAPI[path]; !ok {
 			apiDiffResult.PackagesRemoved = append(apiDiffResult.PackagesRemoved, path)
+			removedAfter, removedWithout := removedPackageDeprecation(path, &oldPkg)
+			apiDiffResult.RemovedAfterDeprecation = append(apiDiffResult.RemovedAfterDeprecation, removedAfter...)
+			apiDiffResult.RemovedWithoutDeprecation = append(apiDiffResult.RemovedWithoutDeprecation, removedWithout...)
+			compat = append(compat, APICompatChange{
+				Path:   path,
+				Kind:   "Removed Packages",
+				Symbol: path,
+				Reason: "importers of the package no longer compile",
+			})
 		}
 	}
 
+	sortCompat(compat)
+	apiDiffResult.Compat = compat
+
 	return apiDiffResult
 }
 
@@ -480,7 +1018,66 @@ func diffList(label, path string, oldList, newList []string) (added, removed []A
 	return added, removed
 }
 
+// diffNamedList is like diffList, but pairs up entries sharing the same symbol name
+// (e.g. "Foo(int)" and "Foo(string)") and reports them as changed instead of added/removed.
+func diffNamedList(label, path string, oldList, newList []string) (added, removed []APIDiffRes, changed []APIDiffChange) {
+	allAdded, allRemoved := diffList(label, path, oldList, newList)
+
+	addedByName := make(map[string]APIDiffRes)
+	for _, a := range allAdded {
+		addedByName[symbolName(a.X)] = a
+	}
+
+	paired := make(map[string]bool)
+	for _, r := range allRemoved {
+		name := symbolName(r.X)
+		a, ok := addedByName[name]
+		if !ok || paired[name] {
+			removed = append(removed, r)
+			continue
+		}
+		paired[name] = true
+		changed = append(changed, APIDiffChange{
+			Label: label,
+			Path:  path,
+			Name:  name,
+			Old:   r.X,
+			New:   a.X,
+		})
+	}
+	for _, a := range allAdded {
+		if !paired[symbolName(a.X)] {
+			added = append(added, a)
+		}
+	}
+
+	return added, removed, changed
+}
+
+// containsName reports whether list has an entry for the symbol name.
+func containsName(list []string, name string) bool {
+	for _, x := range list {
+		if symbolName(x) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// symbolName extracts the identifier from a snapshot entry,
+// e.g. "Foo(int) -> (error)" -> "Foo", "X int" -> "X".
+func symbolName(x string) string {
+	if i := strings.IndexAny(x, "( ["); i >= 0 {
+		return x[:i]
+	}
+	return x
+}
+
 func getModulePath(dir string) string {
+	// "go list -m" prints every module of the workspace when a go.work is in use
+	if path := ModulePath(filepath.Join(dir, "go.mod")); path != "" {
+		return path
+	}
 	cmd := exec.Command("go", "list", "-m")
 	cmd.Dir = dir
 	out, err := cmd.Output()
@@ -499,3 +1096,14 @@ func getGitCommitSHA(dir string) string {
 	}
 	return strings.TrimSpace(string(out))
 }
+
+// getGitPrefix returns the path of dir relative to the repository root, e.g. "tools/" (empty at the root).
+func getGitPrefix(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--show-prefix")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -26,6 +26,24 @@ func TestDiffList(t *testing.T) {
 	assert.Equal(t, "A", removed[0].X)
 }
 
+func TestDiffNamedList(t *testing.T) {
+	oldList := []string{"Foo(int) -> (error)", "Bar()", "Baz()"}
+	newList := []string{"Foo(string) -> (error)", "Bar()", "Qux()"}
+
+	added, removed, changed := diffNamedList("Funcs", "pkg/mypkg", oldList, newList)
+
+	assert.Len(t, added, 1)
+	assert.Equal(t, "Qux()", added[0].X)
+
+	assert.Len(t, removed, 1)
+	assert.Equal(t, "Baz()", removed[0].X)
+
+	require.Len(t, changed, 1)
+	assert.Equal(t, "Foo", changed[0].Name)
+	assert.Equal(t, "Foo(int) -> (error)", changed[0].Old)
+	assert.Equal(t, "Foo(string) -> (error)", changed[0].New)
+}
+
 func TestDiffAPI(t *testing.T) {
 	oldAPI := map[string]APIPackage{
 		"pkg/mypkg": {
@@ -72,6 +90,57 @@ func TestDiffAPI(t *testing.T) {
 	assert.Equal(t, "Baz()", apiDiff.MethodsAdded[0].X)
 }
 
+func TestDiffAPI_Changed(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs:  []string{"Foo(int) -> (error)"},
+			Vars:   []string{"X int"},
+			Consts: []string{"Y string"},
+			Types: map[string]APIType{
+				"MyStruct": {
+					Kind:    "struct",
+					Fields:  []string{"A int"},
+					Methods: []string{"Bar()"},
+				},
+			},
+		},
+	}
+
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs:  []string{"Foo(string) -> (error)"},
+			Vars:   []string{"X int64"},
+			Consts: []string{"Y string"},
+			Types: map[string]APIType{
+				"MyStruct": {
+					Kind:    "struct",
+					Fields:  []string{"A string"},
+					Methods: []string{"Bar() -> (error)"},
+				},
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+
+	assert.Empty(t, apiDiff.FuncsAdded)
+	assert.Empty(t, apiDiff.FuncsRemoved)
+	require.Len(t, apiDiff.FuncsChanged, 1)
+	assert.Equal(t, "Foo", apiDiff.FuncsChanged[0].Name)
+
+	require.Len(t, apiDiff.VarsChanged, 1)
+	assert.Equal(t, "X int", apiDiff.VarsChanged[0].Old)
+	assert.Equal(t, "X int64", apiDiff.VarsChanged[0].New)
+
+	assert.Empty(t, apiDiff.ConstsChanged)
+
+	require.Len(t, apiDiff.FieldsChanged, 1)
+	assert.Equal(t, "A", apiDiff.FieldsChanged[0].Name)
+
+	require.Len(t, apiDiff.MethodsChanged, 1)
+	assert.Equal(t, "Bar", apiDiff.MethodsChanged[0].Name)
+}
+
 func TestAPIDiff_IntegrationTempGit(t *testing.T) {
 	tmpDir := t.TempDir()
 	// t.Log(tmpDir)
@@ -143,6 +212,19 @@ func TestAPIDiff_String(t *testing.T) {
 		TypesRemoved: []APIDiffRes{
 			{Path: "pkg/bar", Label: "Types", X: "OldType"},
 		},
+		FuncsChanged: []APIDiffChange{
+			{Path: "pkg/foo", Label: "Funcs", Name: "Run", Old: "Run(int)", New: "Run(int, string)"},
+			{Path: "pkg/foo", Label: "Funcs", Name: "Use", Old: "Use(*bytes.Buffer)", New: "Use(io.Writer)"},
+		},
+		Compat: []APICompatChange{
+			{Path: "pkg/bar", Kind: "Removed Packages", Symbol: "pkg/bar", Reason: "importers of the package no longer compile"},
+			{Path: "pkg/foo", Kind: "Added Funcs", Symbol: "NewFoo() -> error", Compatible: true, Reason: "new function"},
+			{Path: "pkg/foo", Kind: "Changed Funcs", Symbol: "Run(int) → Run(int, string)", Reason: "parameter count changed from 1 to 2"},
+			{
+				Path: "pkg/foo", Kind: "Changed Funcs", Symbol: "Use(*bytes.Buffer) → Use(io.Writer)", Compatible: true,
+				Reason: "existing arguments still type-check: *bytes.Buffer satisfies io.Writer",
+			},
+		},
 	}
 
 	out := d.String()
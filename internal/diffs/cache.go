@@ -0,0 +1,368 @@
+package diffs
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hashmap-kz/relimpact/internal/loggr"
+	"github.com/hashmap-kz/relimpact/internal/version"
+)
+
+// cacheSchemaVersion must be bumped whenever APIPackage or the cache entry layout changes,
+// so that entries written by older releases are not read back with missing data.
+const cacheSchemaVersion = 6
+
+const (
+	cacheLockTimeout   = 5 * time.Minute  // how long to wait for another process snapshotting the same commit
+	cacheLockHeartbeat = 30 * time.Second // how often the holder refreshes the lock while snapshotting
+	cacheLockStale     = 2 * time.Minute  // a lock not refreshed for this long was left behind by a crashed process
+)
+
+// cacheHeader describes how a cached snapshot was produced. An entry is only used when everything
+// but the checksum matches the current run, and the checksum matches the content.
+type cacheHeader struct {
+	Schema    int    `json:"schema"`
+	Relimpact string `json:"relimpact"`
+	GoVersion string `json:"go_version"`
+	Context   string `json:"context"`  // GOOS, GOARCH, GOFLAGS, CGO_ENABLED and the snapshot options
+	Checksum  string `json:"checksum"` // sha256 of the api JSON
+
+	Created time.Time `json:"created,omitzero"` // when the snapshot was taken; not compared
+}
+
+type cacheEntry struct {
+	Header cacheHeader     `json:"header"`
+	API    json.RawMessage `json:"api"`
+}
+
+// newCacheHeader returns the header a cache entry must have to be reused for dir and opts.
+func newCacheHeader(dir string, opts *SnapshotOptions) cacheHeader {
+	env := goEnv(dir, "GOVERSION", "GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED")
+	ctx := []string{
+		"goos=" + env["GOOS"],
+		"goarch=" + env["GOARCH"],
+		"goflags=" + env["GOFLAGS"],
+		"cgo=" + env["CGO_ENABLED"],
+	}
+	if len(opts.Platforms) > 0 {
+		ctx = append(ctx, "platforms="+platformsKey(opts.Platforms))
+	}
+	if opts.Docs {
+		ctx = append(ctx, "docs")
+	}
+	if opts.Interfaces != nil {
+		ctx = append(ctx, "interfaces="+interfacesKey(opts.Interfaces))
+	}
+	return cacheHeader{
+		Schema:    cacheSchemaVersion,
+		Relimpact: version.Version,
+		GoVersion: env["GOVERSION"],
+		Context:   strings.Join(ctx, ";"),
+	}
+}
+
+// goEnv reads variables of the go command that loads packages in dir.
+func goEnv(dir string, names ...string) map[string]string {
+	cmd := exec.Command("go", append([]string{"env"}, names...)...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		loggr.Warnf("go env failed: %v", err)
+		return map[string]string{}
+	}
+	values := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	env := make(map[string]string, len(names))
+	for i, name := range names {
+		if i < len(values) {
+			env[name] = values[i]
+		}
+	}
+	return env
+}
+
+// mismatch explains why a cached entry cannot be used for want, or returns "".
+func (h *cacheHeader) mismatch(want *cacheHeader) string {
+	switch {
+	case h.Schema != want.Schema:
+		return fmt.Sprintf("schema %d, want %d", h.Schema, want.Schema)
+	case h.Relimpact != want.Relimpact:
+		return fmt.Sprintf("relimpact %s, want %s", h.Relimpact, want.Relimpact)
+	case h.GoVersion != want.GoVersion:
+		return fmt.Sprintf("go %s, want %s", h.GoVersion, want.GoVersion)
+	case h.Context != want.Context:
+		return fmt.Sprintf("build context %q, want %q", h.Context, want.Context)
+	}
+	return ""
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// readCache returns the cached snapshot at path when its header matches want and its content
+// matches the checksum. Entries that don't are skipped, not removed: reading happens outside the lock,
+// so the entry may just have been replaced by another run. cachedSnapshot overwrites them under the lock.
+func readCache(path string, want *cacheHeader) (map[string]APIPackage, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if json.Unmarshal(data, &entry) != nil {
+		loggr.Debugf("cache entry skipped: unreadable entry. path=%s", path)
+		return nil, false
+	}
+	api, reason := decodeCache(&entry, want)
+	if reason != "" {
+		loggr.Debugf("cache entry skipped: %s. path=%s", reason, path)
+		return nil, false
+	}
+	touchCache(path)
+	return api, true
+}
+
+// decodeCache returns the snapshot of entry, or why it cannot be used for want.
+func decodeCache(entry *cacheEntry, want *cacheHeader) (map[string]APIPackage, string) {
+	if reason := entry.Header.mismatch(want); reason != "" {
+		return nil, reason
+	}
+	if checksum(entry.API) != entry.Header.Checksum {
+		return nil, "checksum mismatch"
+	}
+	var api map[string]APIPackage
+	if json.Unmarshal(entry.API, &api) != nil {
+		return nil, "unreadable snapshot"
+	}
+	return api, ""
+}
+
+// newCacheEntry encodes a snapshot with its checksum.
+func newCacheEntry(header cacheHeader, api map[string]APIPackage) (cacheEntry, error) {
+	data, err := json.Marshal(api)
+	if err != nil {
+		return cacheEntry{}, err
+	}
+	header.Checksum = checksum(data)
+	if header.Created.IsZero() {
+		header.Created = time.Now().UTC()
+	}
+	return cacheEntry{Header: header, API: data}, nil
+}
+
+// touchCache records that an entry was used: its modification time is what LRU pruning goes by.
+func touchCache(path string) {
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		loggr.Debugf("cannot touch cache entry: %v", err)
+	}
+}
+
+// writeCache stores a snapshot atomically: concurrent readers see either the old entry or the complete new one.
+func writeCache(path string, header cacheHeader, api map[string]APIPackage) error {
+	e, err := newCacheEntry(header, api)
+	if err != nil {
+		return err
+	}
+	entry, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	if _, err := tmp.Write(entry); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// lockCache takes an exclusive lock file next to a cache entry, waiting while another process holds it.
+// The lock records the holder's PID and host: a lock of a process of this host that is gone (killed, or
+// exited through log.Fatal) is broken at once. The holder refreshes the lock while it holds it, so a lock
+// not refreshed for cacheLockStale, e.g. of a crashed process on another host sharing the cache dir, is
+// broken as well. Every lock also records a random token, so that a lock is only ever broken or released
+// by its content, never by its name alone. The returned func releases the lock.
+func lockCache(path string) (func(), error) {
+	lockPath := path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o750); err != nil {
+		return nil, err
+	}
+	host, _ := os.Hostname()
+	deadline := time.Now().Add(cacheLockTimeout)
+	wait := 50 * time.Millisecond
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			content := fmt.Sprintf("%d %s %s\n", os.Getpid(), host, rand.Text())
+			//nolint:errcheck
+			_, _ = f.WriteString(content)
+			f.Close()
+			return holdLock(lockPath, content), nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if reason, seen := abandonedLock(lockPath, host); reason != "" {
+			broken := removeLock(lockPath, func(moved string) bool {
+				// the lock moved aside must be the one found abandoned, not one taken meanwhile
+				again, content := abandonedLock(moved, host)
+				return again != "" && content == seen
+			})
+			if broken {
+				loggr.Warnf("broke cache lock (%s): %s", reason, lockPath)
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", lockPath)
+		}
+		time.Sleep(wait)
+		wait = min(2*wait, time.Second)
+	}
+}
+
+// holdLock refreshes a taken lock every cacheLockHeartbeat until the returned func releases it.
+// A lock broken meanwhile and taken by another process is left to that process.
+func holdLock(lockPath, content string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(cacheLockHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				//nolint:errcheck
+				_ = os.Chtimes(lockPath, now, now)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		removeLock(lockPath, func(moved string) bool {
+			data, err := os.ReadFile(moved)
+			return err == nil && string(data) == content
+		})
+	}
+}
+
+// removeLock removes the lock at lockPath if owned reports that it is the expected one. Checking the
+// lock and then removing it by name could remove a lock another process took in between, so the lock
+// is first moved to a unique name, where owned checks it; a lock moved aside by mistake is put back.
+func removeLock(lockPath string, owned func(moved string) bool) bool {
+	moved := lockPath + ".tmp-" + rand.Text()
+	if err := os.Rename(lockPath, moved); err != nil {
+		return false // released or broken meanwhile
+	}
+	defer os.Remove(moved)
+	if owned(moved) {
+		return true
+	}
+	// linking, unlike renaming, fails instead of replacing a lock taken while this one was aside
+	if err := os.Link(moved, lockPath); err != nil {
+		loggr.Warnf("cannot restore cache lock %s: %v", lockPath, err)
+	}
+	return false
+}
+
+// abandonedLock explains why a lock held by another process can be broken, or returns "".
+// It also returns the content of the lock it judged.
+func abandonedLock(lockPath, host string) (reason, content string) {
+	// read before stat: a lock replaced in between is fresh, and judged alive
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return "", "" // released meanwhile; retry
+	}
+	content = string(data)
+	info, err := os.Stat(lockPath)
+	if err != nil {
+		return "", content
+	}
+	if time.Since(info.ModTime()) > cacheLockStale {
+		return "stale", content
+	}
+	var pid int
+	var owner string
+	if n, _ := fmt.Sscanf(content, "%d %s", &pid, &owner); n < 1 || pid <= 0 {
+		return "", content // being written, or written by an older release; left to the stale check
+	}
+	if owner != host {
+		return "", content // cannot tell whether a process of another host is alive
+	}
+	if !processAlive(pid) {
+		return fmt.Sprintf("process %d is gone", pid), content
+	}
+	return "", content
+}
+
+// processAlive reports whether a process of this host is running.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false // windows: no such process
+	}
+	if runtime.GOOS == "windows" {
+		//nolint:errcheck
+		_ = p.Release()
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// cachedSnapshot returns the snapshot cached at path, or takes it with snapshot and caches it.
+// The lock keeps concurrent runs from snapshotting the same commit twice or tearing the entry.
+func cachedSnapshot(path string, header *cacheHeader, snapshot func() map[string]APIPackage) map[string]APIPackage {
+	if api, ok := readCache(path, header); ok {
+		loggr.Debugf("cache hit. path=%s", path)
+		return api
+	}
+
+	unlock, err := lockCache(path)
+	if err != nil {
+		loggr.Warnf("cannot lock cache, snapshotting without it: %v", err)
+		return snapshot()
+	}
+	defer unlock()
+
+	// another process may have written the entry while we waited for the lock
+	if api, ok := readCache(path, header); ok {
+		loggr.Debugf("cache hit. path=%s", path)
+		return api
+	}
+
+	loggr.Debugf("cache miss. path=%s", path)
+	api := snapshot()
+	if err := writeCache(path, *header, api); err != nil {
+		loggr.Warnf("cannot write cache: %v", err)
+	}
+	return api
+}
@@ -0,0 +1,186 @@
+package diffs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testHeader() cacheHeader {
+	return cacheHeader{Schema: cacheSchemaVersion, Relimpact: "v1.0.0", GoVersion: "go1.24.0", Context: "goos=linux;goarch=amd64"}
+}
+
+func TestCache_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sha.json")
+	header := testHeader()
+	api := map[string]APIPackage{"example.com/m": {Funcs: []string{"F()"}}}
+
+	require.NoError(t, writeCache(path, header, api))
+	got, ok := readCache(path, &header)
+	require.True(t, ok)
+	assert.Equal(t, api, got)
+
+	// no temp files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestCache_Invalidation(t *testing.T) {
+	api := map[string]APIPackage{"example.com/m": {Funcs: []string{"F()"}}}
+
+	tests := []struct {
+		name   string
+		modify func(h *cacheHeader)
+	}{
+		{"schema", func(h *cacheHeader) { h.Schema++ }},
+		{"relimpact version", func(h *cacheHeader) { h.Relimpact = "v1.1.0" }},
+		{"go version", func(h *cacheHeader) { h.GoVersion = "go1.25.0" }},
+		{"build context", func(h *cacheHeader) { h.Context = "goos=windows;goarch=amd64" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sha.json")
+			written := testHeader()
+			tt.modify(&written)
+			require.NoError(t, writeCache(path, written, api))
+
+			want := testHeader()
+			_, ok := readCache(path, &want)
+			assert.False(t, ok)
+			assert.FileExists(t, path, "mismatching entries are skipped, not removed outside the lock")
+		})
+	}
+}
+
+func TestCache_Checksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sha.json")
+	header := testHeader()
+	require.NoError(t, writeCache(path, header, map[string]APIPackage{"example.com/m": {Funcs: []string{"F()"}}}))
+
+	// tamper with the snapshot, keeping the header
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var entry cacheEntry
+	require.NoError(t, json.Unmarshal(data, &entry))
+	entry.API = json.RawMessage(`{"example.com/m":{"funcs":["G()"]}}`)
+	data, err = json.Marshal(entry)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	_, ok := readCache(path, &header)
+	assert.False(t, ok)
+
+	// and entries in the old, headerless format
+	require.NoError(t, os.WriteFile(path, []byte(`{"example.com/m":{"funcs":["F()"]}}`), 0o600))
+	_, ok = readCache(path, &header)
+	assert.False(t, ok)
+}
+
+func TestCachedSnapshot_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sha.json")
+	header := testHeader()
+	var snapshots atomic.Int32
+	snapshot := func() map[string]APIPackage {
+		snapshots.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return map[string]APIPackage{"example.com/m": {Funcs: []string{"F()"}}}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			api := cachedSnapshot(path, &header, snapshot)
+			assert.Equal(t, []string{"F()"}, api["example.com/m"].Funcs)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), snapshots.Load(), "waiters reuse the entry written under the lock")
+	assert.NoFileExists(t, path+".lock")
+}
+
+func TestLockCache_Stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sha.json")
+	require.NoError(t, os.WriteFile(path+".lock", []byte("1\n"), 0o600))
+	old := time.Now().Add(-2 * cacheLockStale)
+	require.NoError(t, os.Chtimes(path+".lock", old, old))
+
+	unlock, err := lockCache(path)
+	require.NoError(t, err)
+	unlock()
+	assert.NoFileExists(t, path+".lock")
+}
+
+func TestLockCache_DeadProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sha.json")
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	exited := exec.Command("go", "version")
+	require.NoError(t, exited.Run())
+	require.NoError(t, os.WriteFile(path+".lock", []byte(fmt.Sprintf("%d %s\n", exited.Process.Pid, host)), 0o600))
+
+	start := time.Now()
+	unlock, err := lockCache(path)
+	require.NoError(t, err)
+	unlock()
+	assert.Less(t, time.Since(start), time.Second, "a lock of a process that is gone is broken at once")
+}
+
+func TestAbandonedLock(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "sha.json.lock")
+	content := fmt.Sprintf("%d myhost token\n", os.Getpid())
+	require.NoError(t, os.WriteFile(lockPath, []byte(content), 0o600))
+	reason, seen := abandonedLock(lockPath, "myhost")
+	assert.Empty(t, reason, "the holder is alive")
+	assert.Equal(t, content, seen)
+	reason, _ = abandonedLock(lockPath, "otherhost")
+	assert.Empty(t, reason, "processes of other hosts are not checked")
+
+	old := time.Now().Add(-2 * cacheLockStale)
+	require.NoError(t, os.Chtimes(lockPath, old, old))
+	reason, _ = abandonedLock(lockPath, "otherhost")
+	assert.Equal(t, "stale", reason)
+}
+
+func TestRemoveLock_PutsBackLiveLock(t *testing.T) {
+	dir := t.TempDir()
+	lockPath := filepath.Join(dir, "sha.json.lock")
+	require.NoError(t, os.WriteFile(lockPath, []byte("2 otherhost fresh\n"), 0o600))
+
+	// the stale lock that was judged has been replaced by a fresh one of another process
+	assert.False(t, removeLock(lockPath, func(moved string) bool {
+		data, err := os.ReadFile(moved)
+		return err == nil && string(data) == "1 otherhost stale\n"
+	}))
+	data, err := os.ReadFile(lockPath)
+	require.NoError(t, err)
+	assert.Equal(t, "2 otherhost fresh\n", string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the lock moved aside is not left behind")
+}
+
+func TestLockCache_ReleaseKeepsOtherLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sha.json")
+	unlock, err := lockCache(path)
+	require.NoError(t, err)
+
+	// our lock was broken and another process took it
+	require.NoError(t, os.WriteFile(path+".lock", []byte("2 otherhost token\n"), 0o600))
+	unlock()
+	assert.FileExists(t, path+".lock")
+}
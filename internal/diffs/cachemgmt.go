@@ -0,0 +1,245 @@
+package diffs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCacheMaxSize caps the cache at the end of each run when RELIMPACT_API_CACHE_MAX_SIZE is not set.
+const DefaultCacheMaxSize = 1 << 30
+
+// CacheEntry is a cached snapshot, as listed by 'relimpact cache ls'.
+type CacheEntry struct {
+	Path     string    `json:"path"`
+	SHA      string    `json:"sha"`     // the commit the snapshot was taken at
+	Variant  string    `json:"variant"` // the rest of the cache key: module prefix, platforms, docs, interfaces
+	Size     int64     `json:"size"`
+	Created  time.Time `json:"created"`   // when the snapshot was taken, or the modification time for older entries
+	LastUsed time.Time `json:"last_used"` // the modification time, bumped on every cache hit
+}
+
+// CachePrunePolicy selects the entries 'relimpact cache prune' removes. Zero values disable a policy.
+type CachePrunePolicy struct {
+	MaxAge  time.Duration // remove entries not used for longer than this
+	MaxSize int64         // then remove the least recently used entries until the cache fits
+}
+
+// isCacheEntry reports whether a file name in the cache dir is a snapshot, not a lock or a temp file.
+func isCacheEntry(name string) bool {
+	return strings.HasSuffix(name, ".json") && !strings.Contains(name, ".tmp-")
+}
+
+// isCacheLeftover reports whether a file name in the cache dir is a lock, a temp file of a write,
+// or a lock moved aside to be broken or released.
+func isCacheLeftover(name string) bool {
+	return strings.HasSuffix(name, ".lock") || strings.Contains(name, ".json.tmp-") || strings.Contains(name, ".json.lock.tmp-")
+}
+
+// ListCache returns the snapshots cached in dir, most recently used first.
+func ListCache(dir string) ([]CacheEntry, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var res []CacheEntry
+	for _, f := range files {
+		if f.IsDir() || !isCacheEntry(f.Name()) {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue // removed concurrently
+		}
+		key := strings.TrimSuffix(f.Name(), ".json")
+		sha, variant, _ := strings.Cut(key, "-")
+		path := filepath.Join(dir, f.Name())
+		res = append(res, CacheEntry{
+			Path:     path,
+			SHA:      sha,
+			Variant:  variant,
+			Size:     info.Size(),
+			Created:  cacheCreated(path, info.ModTime()),
+			LastUsed: info.ModTime(),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if !res[i].LastUsed.Equal(res[j].LastUsed) {
+			return res[i].LastUsed.After(res[j].LastUsed)
+		}
+		return res[i].Path < res[j].Path
+	})
+	return res, nil
+}
+
+// cacheCreated reads when an entry was written from its header, falling back to fallback.
+func cacheCreated(path string, fallback time.Time) time.Time {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fallback
+	}
+	var entry struct {
+		Header cacheHeader `json:"header"`
+	}
+	if json.Unmarshal(data, &entry) != nil || entry.Header.Created.IsZero() {
+		return fallback
+	}
+	return entry.Header.Created
+}
+
+// PruneCache removes the entries of dir selected by policy, and locks and temp files left behind by
+// crashed runs. It returns the removed entries.
+func PruneCache(dir string, policy CachePrunePolicy, now time.Time) ([]CacheEntry, error) {
+	removeCacheLeftovers(dir, now)
+	entries, err := ListCache(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []CacheEntry
+	var total int64
+	for _, e := range entries {
+		total += e.Size
+	}
+	remove := func(e CacheEntry) {
+		if err := os.Remove(e.Path); err == nil || errors.Is(err, os.ErrNotExist) {
+			removed = append(removed, e)
+			total -= e.Size
+		}
+	}
+
+	var kept []CacheEntry
+	for _, e := range entries {
+		if policy.MaxAge > 0 && now.Sub(e.LastUsed) > policy.MaxAge {
+			remove(e)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	// kept is most recently used first, so evict from the end
+	for i := len(kept) - 1; i >= 0 && policy.MaxSize > 0 && total > policy.MaxSize; i-- {
+		remove(kept[i])
+	}
+	return removed, nil
+}
+
+// removeCacheLeftovers removes locks and temp files older than cacheLockStale, which no live run holds.
+func removeCacheLeftovers(dir string, now time.Time) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		if f.IsDir() || !isCacheLeftover(f.Name()) {
+			continue
+		}
+		if info, err := f.Info(); err == nil && now.Sub(info.ModTime()) > cacheLockStale {
+			//nolint:errcheck
+			_ = os.Remove(filepath.Join(dir, f.Name()))
+		}
+	}
+}
+
+// ClearCache removes every entry, lock and temp file of dir, leaving anything else in it alone.
+func ClearCache(dir string) (int, error) {
+	files, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, f := range files {
+		if f.IsDir() || (!isCacheEntry(f.Name()) && !isCacheLeftover(f.Name())) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, f.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return n, err
+		}
+		if isCacheEntry(f.Name()) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// VerifyCache checks that an entry is readable and matches its checksum, and was written with the
+// current cache schema. It returns why the entry is invalid, or "". Build context and versions are not
+// checked: they depend on the repository the entry is read for.
+func VerifyCache(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err.Error()
+	}
+	var entry cacheEntry
+	var api map[string]APIPackage
+	switch {
+	case json.Unmarshal(data, &entry) != nil:
+		return "unreadable entry"
+	case entry.Header.Schema != cacheSchemaVersion:
+		return fmt.Sprintf("schema %d, want %d", entry.Header.Schema, cacheSchemaVersion)
+	case checksum(entry.API) != entry.Header.Checksum:
+		return "checksum mismatch"
+	case json.Unmarshal(entry.API, &api) != nil:
+		return "unreadable snapshot"
+	}
+	return ""
+}
+
+// CacheMaxSize is the size the cache is capped at after each run: $RELIMPACT_API_CACHE_MAX_SIZE,
+// or DefaultCacheMaxSize. Zero disables the cap.
+func CacheMaxSize() (int64, error) {
+	s := os.Getenv("RELIMPACT_API_CACHE_MAX_SIZE")
+	if s == "" {
+		return DefaultCacheMaxSize, nil
+	}
+	return ParseSize(s)
+}
+
+// ParseSize parses a byte size like 500MB, 2GiB or 1048576. Units are powers of 1024.
+func ParseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	}
+	num := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(num, u.suffix) {
+			num, mult = strings.TrimSpace(strings.TrimSuffix(num, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// FormatSize renders a byte size the way ParseSize reads it.
+func FormatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
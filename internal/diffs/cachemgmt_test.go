@@ -0,0 +1,116 @@
+package diffs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestEntry caches a snapshot of size bytes or more, last used age ago.
+func writeTestEntry(t *testing.T, dir, name string, size int, age time.Duration, now time.Time) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	funcs := []string{strings.Repeat("x", size)}
+	require.NoError(t, writeCache(path, testHeader(), map[string]APIPackage{"example.com/m": {Funcs: funcs}}))
+	used := now.Add(-age)
+	require.NoError(t, os.Chtimes(path, used, used))
+	return path
+}
+
+func TestListCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeTestEntry(t, dir, "aaa.json", 10, time.Hour, now)
+	writeTestEntry(t, dir, "bbb-1a2b3c-docs.json", 10, time.Minute, now)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "aaa.json.lock"), []byte("1\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "aaa.json.tmp-123"), nil, 0o600))
+
+	entries, err := ListCache(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "bbb", entries[0].SHA, "most recently used first")
+	assert.Equal(t, "1a2b3c-docs", entries[0].Variant)
+	assert.Equal(t, "aaa", entries[1].SHA)
+	assert.Empty(t, entries[1].Variant)
+	assert.WithinDuration(t, now, entries[1].Created, time.Minute, "taken from the header, not the last use")
+
+	entries, err = ListCache(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestPruneCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	writeTestEntry(t, dir, "new.json", 1000, time.Minute, now)
+	writeTestEntry(t, dir, "mid.json", 1000, time.Hour, now)
+	writeTestEntry(t, dir, "old.json", 1000, 48*time.Hour, now)
+	stale := filepath.Join(dir, "gone.json.lock")
+	require.NoError(t, os.WriteFile(stale, []byte("1\n"), 0o600))
+	require.NoError(t, os.Chtimes(stale, now.Add(-time.Hour), now.Add(-time.Hour)))
+	aside := filepath.Join(dir, "gone.json.lock.tmp-123")
+	require.NoError(t, os.WriteFile(aside, []byte("1\n"), 0o600))
+	require.NoError(t, os.Chtimes(aside, now.Add(-time.Hour), now.Add(-time.Hour)))
+
+	removed, err := PruneCache(dir, CachePrunePolicy{MaxAge: 24 * time.Hour}, now)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, "old", removed[0].SHA)
+	assert.NoFileExists(t, stale, "locks of crashed runs are removed")
+	assert.NoFileExists(t, aside, "and locks a crashed run moved aside")
+
+	// LRU: only the most recently used entry fits
+	removed, err = PruneCache(dir, CachePrunePolicy{MaxSize: 1500}, now)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, "mid", removed[0].SHA)
+	assert.FileExists(t, filepath.Join(dir, "new.json"))
+}
+
+func TestReadCache_Touches(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	path := writeTestEntry(t, dir, "sha.json", 10, 48*time.Hour, now)
+	header := testHeader()
+	_, ok := readCache(path, &header)
+	require.True(t, ok)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now, info.ModTime(), time.Minute, "a cache hit counts as a use for LRU pruning")
+}
+
+func TestVerifyAndClearCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	good := writeTestEntry(t, dir, "good.json", 10, 0, now)
+	bad := writeTestEntry(t, dir, "bad.json", 10, 0, now)
+	require.NoError(t, os.WriteFile(bad, []byte(fmt.Sprintf(`{"header":{"schema":%d,"checksum":"x"},"api":{}}`, cacheSchemaVersion)), 0o600))
+	other := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(other, nil, 0o600))
+
+	assert.Empty(t, VerifyCache(good))
+	assert.Equal(t, "checksum mismatch", VerifyCache(bad))
+
+	n, err := ClearCache(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.FileExists(t, other, "files that are not cache entries are left alone")
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"1048576": 1 << 20, "500MB": 500 << 20, "2GiB": 2 << 30, "1.5k": 1536, "0": 0} {
+		got, err := ParseSize(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, got, s)
+	}
+	_, err := ParseSize("lots")
+	assert.Error(t, err)
+	assert.Equal(t, "1.5KiB", FormatSize(1536))
+}
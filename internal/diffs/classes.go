@@ -0,0 +1,109 @@
+package diffs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Package classes: only public packages can be imported by other modules,
+// so only their changes count toward breaking changes and the suggested version.
+const (
+	ClassPublic   = "public"
+	ClassInternal = "internal"
+	ClassCommand  = "command"
+	ClassExample  = "example"
+)
+
+var classSections = map[string]struct {
+	heading string
+	note    string
+}{
+	ClassInternal: {"Internal Packages", "Not importable outside this module, so these changes are never breaking."},
+	ClassCommand:  {"Commands", "`package main` programs; nothing in them can be imported."},
+	ClassExample:  {"Examples", "Example code, not part of the public API."},
+}
+
+// classifyPackage tells public packages apart from internal/ packages, commands and examples.
+// Examples are the packages of a trailing example/, examples/ or *_example/ dir, and the commands
+// anywhere below such a dir. Only the path within the module counts, not the module path itself.
+func classifyPackage(pkgPath, modulePath, name string) string {
+	rel := strings.Trim(strings.TrimPrefix(pkgPath, modulePath), "/")
+	elems := strings.Split(rel, "/")
+	if name == "main" {
+		for _, elem := range elems {
+			if isExampleDir(elem) {
+				return ClassExample
+			}
+		}
+		return ClassCommand
+	}
+	for _, elem := range elems {
+		if elem == "internal" {
+			return ClassInternal
+		}
+	}
+	if isExampleDir(elems[len(elems)-1]) {
+		return ClassExample
+	}
+	return ClassPublic
+}
+
+func isExampleDir(elem string) bool {
+	return elem == "example" || elem == "examples" || strings.HasSuffix(elem, "_example")
+}
+
+// FilterClass returns the packages of api that belong to class.
+// Snapshots taken before packages were classified count as public.
+func FilterClass(api map[string]APIPackage, class string) map[string]APIPackage {
+	res := make(map[string]APIPackage)
+	for path, pkg := range api {
+		c := pkg.Class
+		if c == "" {
+			c = ClassPublic
+		}
+		if c == class {
+			res[path] = pkg
+		}
+	}
+	return res
+}
+
+// ClassSection renders the changes of non-public packages as a single section,
+// with one collapsed list per package and no compatibility verdicts.
+func (d *APIDiff) ClassSection(class string) string {
+	grouped := d.packageChanges()
+	changed := groupChanges(d.GoSyntax, d.Links,
+		d.FuncsChanged, d.VarsChanged, d.ConstsChanged, d.FieldsChanged, d.MethodsChanged, d.ReceiversChanged,
+		d.TypesKindChanged, d.TypesUnderlyingChanged, d.TypeParamsChanged, d.FieldTagsChanged, d.ConstValuesChanged)
+	for pkg, labels := range changed {
+		if _, ok := grouped[pkg]; !ok {
+			grouped[pkg] = make(map[string][]string)
+		}
+		for label, xs := range labels {
+			grouped[pkg][label] = append(grouped[pkg][label], xs...)
+		}
+	}
+	if len(grouped) == 0 && len(d.PackagesAdded) == 0 && len(d.PackagesRemoved) == 0 {
+		return ""
+	}
+
+	section := classSections[class]
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n### %s\n\n_%s_\n", section.heading, section.note))
+	if len(d.PackagesAdded)+len(d.PackagesRemoved) > 0 {
+		sb.WriteString("\n")
+		for _, x := range []struct {
+			kind     string
+			packages []string
+		}{{"Added", d.PackagesAdded}, {"Removed", d.PackagesRemoved}} {
+			sorted := append([]string{}, x.packages...)
+			sort.Strings(sorted)
+			for _, pkg := range sorted {
+				sb.WriteString(fmt.Sprintf("- %s `%s`\n", x.kind, pkg))
+			}
+		}
+	}
+	writeGrouped(&sb, grouped)
+	return sb.String()
+}
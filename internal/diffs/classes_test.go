@@ -0,0 +1,84 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyPackage(t *testing.T) {
+	tests := []struct {
+		pkgPath string
+		name    string
+		want    string
+	}{
+		{"example.com/m", "m", ClassPublic},
+		{"example.com/m/pkg/api", "api", ClassPublic},
+		{"example.com/m/internal", "internal", ClassInternal},
+		{"example.com/m/pkg/internal/store", "store", ClassInternal},
+		{"example.com/m/cmd/tool", "main", ClassCommand},
+		{"example.com/m/internal/tool", "main", ClassCommand},
+		{"example.com/m/examples/basic", "main", ClassExample},
+		{"example.com/m/examples", "examples", ClassExample},
+		{"example.com/m/example", "example", ClassExample},
+		{"example.com/m/pkg/grpc_example", "grpc_example", ClassExample},
+		{"example.com/m/examples/shared", "shared", ClassPublic},
+		{"example.com/m/internalx", "internalx", ClassPublic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pkgPath+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, classifyPackage(tt.pkgPath, "example.com/m", tt.name))
+		})
+	}
+}
+
+func TestClassifyPackage_ExampleModule(t *testing.T) {
+	const module = "github.com/org/example"
+	assert.Equal(t, ClassPublic, classifyPackage(module, module, "example"))
+	assert.Equal(t, ClassPublic, classifyPackage(module+"/client", module, "client"))
+	assert.Equal(t, ClassCommand, classifyPackage(module+"/cmd/tool", module, "main"))
+	assert.Equal(t, ClassExample, classifyPackage(module+"/examples/basic", module, "main"))
+}
+
+func TestFilterClass(t *testing.T) {
+	api := map[string]APIPackage{
+		"example.com/m":          {},
+		"example.com/m/api":      {Class: ClassPublic},
+		"example.com/m/internal": {Class: ClassInternal},
+		"example.com/m/cmd/tool": {Class: ClassCommand},
+	}
+	assert.Len(t, FilterClass(api, ClassPublic), 2)
+	assert.Contains(t, FilterClass(api, ClassInternal), "example.com/m/internal")
+	assert.Empty(t, FilterClass(api, ClassExample))
+}
+
+func TestAPIDiff_ClassSection(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"example.com/m/internal/store": {
+			Class: ClassInternal,
+			Funcs: []string{"Open(string) -> (error)", "Close()"},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"example.com/m/internal/store": {
+			Class: ClassInternal,
+			Funcs: []string{"Open(string, int) -> (error)"},
+		},
+		"example.com/m/internal/cache": {
+			Class: ClassInternal,
+		},
+	}
+
+	d := DiffAPI(oldAPI, newAPI)
+	require.NotEmpty(t, d.Incompatible())
+
+	out := d.ClassSection(ClassInternal)
+	assert.Contains(t, out, "### Internal Packages")
+	assert.Contains(t, out, "- Added `example.com/m/internal/cache`")
+	assert.Contains(t, out, "- Removed Funcs:\n    - Close()")
+	assert.Contains(t, out, "`Open(string) -> (error)` → `Open(string, int) -> (error)`")
+	assert.NotContains(t, out, "Breaking Changes")
+
+	assert.Empty(t, DiffAPI(oldAPI, oldAPI).ClassSection(ClassInternal))
+}
@@ -0,0 +1,422 @@
+package diffs
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// APICompatChange is a single API change classified by the Go compatibility rules
+// (see golang.org/x/exp/apidiff): whether existing importers keep compiling, and why.
+type APICompatChange struct {
+	Path       string `json:"path"`
+	Kind       string `json:"kind"`   // e.g. "Removed Funcs", "Changed Type `T` Fields"
+	Symbol     string `json:"symbol"` // entry, or "old → new" for changed entries
+	Compatible bool   `json:"compatible"`
+	Reason     string `json:"reason"`
+	Pos        string `json:"pos,omitempty"` // "file:line" at the old ref for removals, at the new ref otherwise
+}
+
+// APITypeFacts holds what the compatibility rules need to know about a type used by the API
+// (parameters, results, vars and fields), since the snapshot does not keep the types.Type itself.
+// Every fact is computed with go/types while snapshotting.
+type APITypeFacts struct {
+	Interface bool     `json:"interface,omitempty"`
+	Methods   []string `json:"methods,omitempty"` // method set of the type; for interfaces, the methods it requires
+
+	Named      bool   `json:"named,omitempty"`      // a defined type or type parameter, after resolving aliases
+	Identity   string `json:"identity,omitempty"`   // the type with aliases resolved, when it is spelled differently
+	Underlying string `json:"underlying,omitempty"` // the underlying type, when it differs from the type
+
+	// AssignableTo lists the other types of the package's API that values of the type are assignable to,
+	// as decided by types.AssignableTo. Empty interfaces are left out: everything is assignable to them.
+	AssignableTo []string `json:"assignable_to,omitempty"`
+}
+
+// APISignature gives the parameter and result types of a func or method, as keys of TypeFacts.
+type APISignature struct {
+	Params   []string `json:"params,omitempty"`
+	Results  []string `json:"results,omitempty"`
+	Variadic bool     `json:"variadic,omitempty"`
+}
+
+// Incompatible returns the changes that break existing importers.
+func (d *APIDiff) Incompatible() []APICompatChange {
+	var res []APICompatChange
+	for _, c := range d.Compat {
+		if !c.Compatible {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Compatible returns the changes that keep existing importers compiling.
+func (d *APIDiff) Compatible() []APICompatChange {
+	var res []APICompatChange
+	for _, c := range d.Compat {
+		if c.Compatible {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// recordType records the facts of a type used by the API and returns its key in TypeFacts.
+func (p *APIPackage) recordType(t types.Type) string {
+	key := t.String()
+	if _, ok := p.TypeFacts[key]; ok {
+		return key
+	}
+	f := APITypeFacts{Interface: types.IsInterface(t)}
+	ms := types.NewMethodSet(t)
+	for i := 0; i < ms.Len(); i++ {
+		m := ms.At(i).Obj()
+		//nolint:errcheck
+		f.Methods = append(f.Methods, m.Name()+signatureString(m.Type().(*types.Signature)))
+	}
+	switch types.Unalias(t).(type) {
+	case *types.Named, *types.TypeParam:
+		f.Named = true
+	}
+	if id := types.Unalias(t).String(); id != key {
+		f.Identity = id
+	}
+	if u := t.Underlying().String(); u != key {
+		f.Underlying = u
+	}
+
+	if p.TypeFacts == nil {
+		p.TypeFacts = make(map[string]APITypeFacts)
+	}
+	if p.factTypes == nil {
+		p.factTypes = make(map[string]types.Type)
+	}
+	p.TypeFacts[key] = f
+	p.factTypes[key] = t
+	return key
+}
+
+// recordSignature records the parameter and result types of a func, or of a method keyed "Type.Name".
+func (p *APIPackage) recordSignature(key string, sig *types.Signature) {
+	asig := APISignature{Variadic: sig.Variadic()}
+	for i := 0; i < sig.Params().Len(); i++ {
+		asig.Params = append(asig.Params, p.recordType(sig.Params().At(i).Type()))
+	}
+	for i := 0; i < sig.Results().Len(); i++ {
+		asig.Results = append(asig.Results, p.recordType(sig.Results().At(i).Type()))
+	}
+	if p.Signatures == nil {
+		p.Signatures = make(map[string]APISignature)
+	}
+	p.Signatures[key] = asig
+}
+
+// recordValue records the type of a var, or of a field keyed "Type.Name".
+func (p *APIPackage) recordValue(key string, t types.Type) {
+	if p.ValueTypes == nil {
+		p.ValueTypes = make(map[string]string)
+	}
+	p.ValueTypes[key] = p.recordType(t)
+}
+
+// relateTypes records which types of the API are assignable to which, once every type is recorded.
+func (p *APIPackage) relateTypes() {
+	keys := make([]string, 0, len(p.factTypes))
+	for key := range p.factTypes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, v := range keys {
+		vf := p.TypeFacts[v]
+		for _, t := range keys {
+			tf := p.TypeFacts[t]
+			if v == t || isEmptyInterface(&tf) {
+				continue
+			}
+			// types.AssignableTo can only hold for these; skip the rest cheaply
+			if !tf.Interface && tf.underlying(t) != vf.underlying(v) && !isChan(p.factTypes[v]) {
+				continue
+			}
+			if types.AssignableTo(p.factTypes[v], p.factTypes[t]) {
+				vf.AssignableTo = append(vf.AssignableTo, t)
+			}
+		}
+		p.TypeFacts[v] = vf
+	}
+	p.factTypes = nil
+}
+
+// paramType spells parameter i, showing the variadic one as ...T.
+func (s *APISignature) paramType(i int) string {
+	if s.Variadic && i == len(s.Params)-1 {
+		return "..." + strings.TrimPrefix(s.Params[i], "[]")
+	}
+	return s.Params[i]
+}
+
+func (f *APITypeFacts) identity(key string) string {
+	if f.Identity != "" {
+		return f.Identity
+	}
+	return key
+}
+
+func (f *APITypeFacts) underlying(key string) string {
+	if f.Underlying != "" {
+		return f.Underlying
+	}
+	return key
+}
+
+func isEmptyInterface(f *APITypeFacts) bool {
+	return f.Interface && len(f.Methods) == 0
+}
+
+func isChan(t types.Type) bool {
+	_, ok := t.Underlying().(*types.Chan)
+	return ok
+}
+
+// identical reports whether type x, recorded in xPkg, and type y, recorded in yPkg, are the same type
+// once aliases are resolved.
+func identical(x string, xPkg *APIPackage, y string, yPkg *APIPackage) bool {
+	xf, okX := xPkg.TypeFacts[x]
+	yf, okY := yPkg.TypeFacts[y]
+	return x == y || okX && okY && xf.identity(x) == yf.identity(y)
+}
+
+// assignable reports whether values of type v, recorded in from, are assignable to type t, recorded in to.
+// The packages may be snapshots of different refs. When both types are recorded in one of them, the
+// types.AssignableTo verdict taken while snapshotting decides; otherwise the assignability rules of the
+// Go spec are applied to the facts of each side.
+func assignable(v string, from *APIPackage, t string, to *APIPackage) bool {
+	if v == t {
+		return true
+	}
+	tf, ok := to.TypeFacts[t]
+	if ok && isEmptyInterface(&tf) {
+		return true
+	}
+	for _, p := range []*APIPackage{to, from} {
+		pv, okV := p.TypeFacts[v]
+		_, okT := p.TypeFacts[t]
+		if okV && okT {
+			return slices.Contains(pv.AssignableTo, t)
+		}
+	}
+
+	vf, okV := from.TypeFacts[v]
+	if !ok || !okV {
+		return false
+	}
+	switch {
+	case identical(v, from, t, to):
+		return true
+	case tf.Interface:
+		have := make(map[string]bool, len(vf.Methods))
+		for _, m := range vf.Methods {
+			have[m] = true
+		}
+		for _, m := range tf.Methods {
+			if !have[m] {
+				return false
+			}
+		}
+		return true
+	default:
+		// identical underlying types, and at least one of them is not a defined type
+		return (!vf.Named || !tf.Named) && vf.underlying(v) == tf.underlying(t)
+	}
+}
+
+func compatRemoved(kind string, items []APIDiffRes, reason string) []APICompatChange {
+	res := make([]APICompatChange, 0, len(items))
+	for _, r := range items {
+		res = append(res, APICompatChange{Path: r.Path, Kind: "Removed " + kind, Symbol: r.X, Reason: reason, Pos: r.Pos})
+	}
+	return res
+}
+
+func compatAdded(kind string, items []APIDiffRes, reason string) []APICompatChange {
+	res := make([]APICompatChange, 0, len(items))
+	for _, r := range items {
+		res = append(res, APICompatChange{Path: r.Path, Kind: "Added " + kind, Symbol: r.X, Compatible: true, Reason: reason, Pos: r.Pos})
+	}
+	return res
+}
+
+func compatChanged(kind string, c *APIDiffChange, compatible bool, reason string) APICompatChange {
+	return APICompatChange{
+		Path:       c.Path,
+		Kind:       "Changed " + kind,
+		Symbol:     fmt.Sprintf("%s → %s", c.Old, c.New),
+		Compatible: compatible,
+		Reason:     reason,
+		Pos:        c.NewPos,
+	}
+}
+
+// compatSignatures classifies changed func signatures, or the methods of typeName when set.
+func compatSignatures(kind, typeName string, changed []APIDiffChange, oldPkg, newPkg *APIPackage) []APICompatChange {
+	res := make([]APICompatChange, 0, len(changed))
+	for i := range changed {
+		key := changed[i].Name
+		if typeName != "" {
+			key = typeName + "." + key
+		}
+		oldSig, okOld := oldPkg.Signatures[key]
+		newSig, okNew := newPkg.Signatures[key]
+		ok, reason := false, "signature changed"
+		if okOld && okNew {
+			ok, reason = compatSignature(&oldSig, &newSig, oldPkg, newPkg)
+		}
+		res = append(res, compatChanged(kind, &changed[i], ok, reason))
+	}
+	return res
+}
+
+// compatValues classifies changed var types, or the field types of typeName when set. Any change of
+// the type breaks: besides reads, assignments, &x and composite literals setting a field use it.
+// Only a change of spelling is compatible, e.g. an alias replaced with the type it stands for.
+func compatValues(kind, typeName string, changed []APIDiffChange, oldPkg, newPkg *APIPackage, reason string) []APICompatChange {
+	res := make([]APICompatChange, 0, len(changed))
+	for i := range changed {
+		key := changed[i].Name
+		if typeName != "" {
+			key = typeName + "." + key
+		}
+		oldT, newT := oldPkg.ValueTypes[key], newPkg.ValueTypes[key]
+		if oldT != "" && newT != "" && identical(oldT, oldPkg, newT, newPkg) {
+			res = append(res, compatChanged(kind, &changed[i], true,
+				fmt.Sprintf("%s and %s are identical types", oldT, newT)))
+			continue
+		}
+		res = append(res, compatChanged(kind, &changed[i], false, reason))
+	}
+	return res
+}
+
+// compatIncompatible marks every changed entry as breaking, for the same reason.
+func compatIncompatible(kind string, changed []APIDiffChange, reason string) []APICompatChange {
+	res := make([]APICompatChange, 0, len(changed))
+	for i := range changed {
+		res = append(res, compatChanged(kind, &changed[i], false, reason))
+	}
+	return res
+}
+
+// compatType classifies the method changes of a type present on both sides,
+// plus type-level properties like comparability.
+func compatType(path, tname string, oldType, newType *APIType, oldPkg, newPkg *APIPackage,
+	methodsAdded, methodsRemoved []APIDiffRes, methodsChanged []APIDiffChange,
+) []APICompatChange {
+	var res []APICompatChange
+	kind := fmt.Sprintf("Type `%s` Methods", tname)
+
+	if oldType.Comparable && !newType.Comparable {
+		res = append(res, APICompatChange{
+			Path:   path,
+			Kind:   "Changed Types",
+			Symbol: tname,
+			Reason: "type is no longer comparable; == and map keys using it no longer compile",
+			Pos:    newPkg.Positions[tname],
+		})
+	}
+
+	if oldType.Kind != "interface" || newType.Kind != "interface" {
+		res = append(res, compatRemoved(kind, methodsRemoved, "calls to the method no longer compile, and the type may stop satisfying interfaces")...)
+		res = append(res, compatAdded(kind, methodsAdded, "new method")...)
+		return append(res, compatSignatures(kind, tname, methodsChanged, oldPkg, newPkg)...)
+	}
+
+	res = append(res, compatRemoved(kind, methodsRemoved, "calls to the interface method no longer compile")...)
+	if isSealed(oldType) {
+		res = append(res, compatAdded(kind, methodsAdded, "interface has unexported methods, so it cannot be implemented outside its package")...)
+	} else {
+		for _, r := range methodsAdded {
+			res = append(res, APICompatChange{
+				Path:   r.Path,
+				Kind:   "Added " + kind,
+				Symbol: r.X,
+				Reason: "adding a method to an interface breaks every implementation outside the package",
+				Pos:    r.Pos,
+			})
+		}
+	}
+	return append(res, compatIncompatible(kind, methodsChanged, "interface method signature changed; implementations and callers break")...)
+}
+
+// compatSignature decides whether a signature change keeps existing call sites compiling.
+// Arity changes break. Existing arguments must stay assignable to the parameters
+// (e.g. *bytes.Buffer -> io.Writer), and the results to what callers assign them to.
+func compatSignature(oldSig, newSig *APISignature, oldPkg, newPkg *APIPackage) (compatible bool, reason string) {
+	if len(oldSig.Params) != len(newSig.Params) {
+		return false, fmt.Sprintf("parameter count changed from %d to %d", len(oldSig.Params), len(newSig.Params))
+	}
+	if len(oldSig.Results) != len(newSig.Results) {
+		return false, fmt.Sprintf("result count changed from %d to %d", len(oldSig.Results), len(newSig.Results))
+	}
+	if oldSig.Variadic != newSig.Variadic {
+		last := len(oldSig.Params) - 1
+		return false, fmt.Sprintf("parameter %d type changed from %s to %s",
+			last+1, oldSig.paramType(last), newSig.paramType(last))
+	}
+
+	var notes []string
+	for i, oldT := range oldSig.Params {
+		newT := newSig.Params[i]
+		if oldT == newT {
+			continue
+		}
+		if !assignable(oldT, oldPkg, newT, newPkg) {
+			return false, fmt.Sprintf("parameter %d type changed from %s to %s", i+1, oldT, newT)
+		}
+		notes = append(notes, fmt.Sprintf("%s is assignable to %s", oldT, newT))
+	}
+	for i, oldT := range oldSig.Results {
+		newT := newSig.Results[i]
+		if oldT == newT {
+			continue
+		}
+		if !assignable(newT, newPkg, oldT, oldPkg) {
+			return false, fmt.Sprintf("result %d type changed from %s to %s", i+1, oldT, newT)
+		}
+		notes = append(notes, fmt.Sprintf("result %s is assignable to %s", newT, oldT))
+	}
+	if len(notes) == 0 {
+		return false, "signature changed"
+	}
+	return true, "existing calls still type-check: " + strings.Join(notes, ", ")
+}
+
+// isSealed reports whether an interface has unexported methods,
+// which makes it impossible to implement outside its package.
+func isSealed(t *APIType) bool {
+	for _, m := range t.Methods {
+		if !token.IsExported(symbolName(m)) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortCompat(items []APICompatChange) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Path != items[j].Path {
+			return items[i].Path < items[j].Path
+		}
+		if items[i].Kind != items[j].Kind {
+			return items[i].Kind < items[j].Kind
+		}
+		return items[i].Symbol < items[j].Symbol
+	})
+}
+
+// compatSymbol renders the symbol of a classified change, with its position when known.
+func (d *APIDiff) compatSymbol(c *APICompatChange) string {
+	return d.Links.withPosition("`"+c.Symbol+"`", c.Pos, strings.HasPrefix(c.Kind, "Removed "))
+}
@@ -0,0 +1,197 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+// snapshotSource snapshots the package in src, as SnapshotAPI would.
+func snapshotSource(t *testing.T, src string) map[string]APIPackage {
+	t.Helper()
+	pkg := &packages.Package{PkgPath: "example.com/p", Name: "p", Types: typeCheck(t, src)}
+	return snapshotPackages([]*packages.Package{pkg}, "example.com/p", &SnapshotOptions{})
+}
+
+func TestRecordTypeFacts(t *testing.T) {
+	api := snapshotSource(t, `package p
+
+type Writer interface{ Write(p []byte) (int, error) }
+
+type Buf struct{}
+
+func (*Buf) Write(p []byte) (int, error) { return 0, nil }
+
+type ID int
+
+func Open(id ID, n int) Writer { return nil }
+
+var Out *Buf
+`)
+	pkg := api["example.com/p"]
+
+	assert.Equal(t, APISignature{
+		Params:  []string{"example.com/p.ID", "int"},
+		Results: []string{"example.com/p.Writer"},
+	}, pkg.Signatures["Open"])
+	assert.Contains(t, pkg.Signatures, "Buf.Write")
+	assert.Equal(t, "*example.com/p.Buf", pkg.ValueTypes["Out"])
+
+	assert.Contains(t, pkg.TypeFacts["*example.com/p.Buf"].AssignableTo, "example.com/p.Writer")
+	assert.NotContains(t, pkg.TypeFacts["int"].AssignableTo, "example.com/p.ID")
+	assert.True(t, pkg.TypeFacts["example.com/p.ID"].Named)
+	assert.Equal(t, "int", pkg.TypeFacts["example.com/p.ID"].Underlying)
+}
+
+func TestCompatSignature(t *testing.T) {
+	oldPkg := &APIPackage{TypeFacts: map[string]APITypeFacts{
+		"*bytes.Buffer": {Named: true, Methods: []string{"String() -> (string)", "Write([]byte) -> (int, error)"}},
+		"int":           {},
+		"[]byte":        {},
+	}}
+	newPkg := &APIPackage{TypeFacts: map[string]APITypeFacts{
+		"io.Writer": {Interface: true, Named: true, Methods: []string{"Write([]byte) -> (int, error)"}},
+		"io.Reader": {Interface: true, Named: true, Methods: []string{"Read([]byte) -> (int, error)"}},
+		"any":       {Interface: true, Identity: "interface{}"},
+		"string":    {},
+		"error":     {Interface: true, Named: true, Methods: []string{"Error() -> (string)"}},
+		"Bytes":     {Named: true, Underlying: "[]byte"},
+	}}
+
+	tests := []struct {
+		name       string
+		oldSig     APISignature
+		newSig     APISignature
+		compatible bool
+	}{
+		{"param to satisfied interface", APISignature{Params: []string{"*bytes.Buffer"}}, APISignature{Params: []string{"io.Writer"}}, true},
+		{"param to unsatisfied interface", APISignature{Params: []string{"*bytes.Buffer"}}, APISignature{Params: []string{"io.Reader"}}, false},
+		{"param to any", APISignature{Params: []string{"int"}}, APISignature{Params: []string{"any"}}, true},
+		{"param to concrete type", APISignature{Params: []string{"int"}}, APISignature{Params: []string{"string"}}, false},
+		{"param to defined type of same underlying", APISignature{Params: []string{"[]byte"}}, APISignature{Params: []string{"Bytes"}}, true},
+		{"result to interface", APISignature{Results: []string{"*bytes.Buffer"}}, APISignature{Results: []string{"io.Writer"}}, false},
+		{"result added", APISignature{Results: []string{"int"}}, APISignature{Results: []string{"int", "error"}}, false},
+		{"param added", APISignature{Params: []string{"int"}}, APISignature{Params: []string{"int", "int"}}, false},
+		{"made variadic", APISignature{Params: []string{"[]byte"}}, APISignature{Params: []string{"[]byte"}, Variadic: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reason := compatSignature(&tt.oldSig, &tt.newSig, oldPkg, newPkg)
+			assert.Equal(t, tt.compatible, ok, reason)
+			assert.NotEmpty(t, reason)
+		})
+	}
+}
+
+func TestDiffAPI_CompatAssignable(t *testing.T) {
+	oldAPI := snapshotSource(t, `package p
+
+type Writer interface{ Write(p []byte) (int, error) }
+
+type Buf struct{}
+
+func (*Buf) Write(p []byte) (int, error) { return 0, nil }
+
+type Config struct {
+	Out   Writer
+	Limit int
+}
+
+var Out Writer
+var Count int
+var Size int
+
+func Open() Writer { return nil }
+func Use(b *Buf) {}
+func Close() *Buf { return nil }
+`)
+	newAPI := snapshotSource(t, `package p
+
+type Writer interface{ Write(p []byte) (int, error) }
+
+type Buf struct{}
+
+func (*Buf) Write(p []byte) (int, error) { return 0, nil }
+
+type Config struct {
+	Out   *Buf
+	Limit string
+}
+
+type Int = int
+
+var Out *Buf
+var Count string
+var Size Int
+
+func Open() *Buf { return nil }
+func Use(w Writer) {}
+func Close() Writer { return nil }
+`)
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	compatible := map[string]bool{}
+	for _, c := range apiDiff.Compat {
+		compatible[c.Kind+" "+symbolName(c.Symbol)] = c.Compatible
+	}
+
+	assert.Equal(t, map[string]bool{
+		"Changed Vars Out":                   false, // p.Out = otherWriter no longer compiles
+		"Changed Vars Count":                 false, // int -> string
+		"Changed Vars Size":                  true,  // the alias Int is int
+		"Added Types Int":                    true,
+		"Changed Funcs Open":                 true,  // the *Buf result satisfies Writer
+		"Changed Funcs Use":                  true,  // a *Buf argument is assignable to Writer
+		"Changed Funcs Close":                false, // callers may rely on *Buf
+		"Changed Type `Config` Fields Out":   false, // Config{Out: otherWriter} no longer compiles
+		"Changed Type `Config` Fields Limit": false,
+	}, compatible)
+}
+
+func TestDiffAPI_Compat(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs: []string{"Foo()", "Gone()"},
+			Types: map[string]APIType{
+				"Iface":  {Kind: "interface", Methods: []string{"A()"}},
+				"Sealed": {Kind: "interface", Methods: []string{"A()", "sealed()"}},
+				"Key":    {Kind: "struct", Fields: []string{"ID int"}, Comparable: true},
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs: []string{"Foo()", "New()"},
+			Types: map[string]APIType{
+				"Iface":  {Kind: "interface", Methods: []string{"A()", "B()"}},
+				"Sealed": {Kind: "interface", Methods: []string{"A()", "B()", "sealed()"}},
+				"Key":    {Kind: "struct", Fields: []string{"ID int", "Tags []string"}, Comparable: false},
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+
+	find := func(kind, symbol string) APICompatChange {
+		t.Helper()
+		for _, c := range apiDiff.Compat {
+			if c.Kind == kind && c.Symbol == symbol {
+				return c
+			}
+		}
+		require.Failf(t, "change not found", "%s %s", kind, symbol)
+		return APICompatChange{}
+	}
+
+	assert.False(t, find("Removed Funcs", "Gone()").Compatible)
+	assert.True(t, find("Added Funcs", "New()").Compatible)
+	assert.False(t, find("Added Type `Iface` Methods", "B()").Compatible)
+	assert.True(t, find("Added Type `Sealed` Methods", "B()").Compatible)
+	assert.True(t, find("Added Type `Key` Fields", "Tags []string").Compatible)
+	assert.False(t, find("Changed Types", "Key").Compatible)
+
+	assert.Len(t, apiDiff.Incompatible(), 3)
+}
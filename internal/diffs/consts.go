@@ -0,0 +1,166 @@
+package diffs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// APIConstShift is a group of constants of one iota block whose values changed together,
+// because a constant was inserted, removed or moved.
+type APIConstShift struct {
+	Path      string          `json:"path"`
+	Block     string          `json:"block"` // first exported constant of the block
+	Type      string          `json:"type"`
+	Reordered bool            `json:"reordered"` // same set of values, assigned to different names
+	Consts    []APIDiffChange `json:"consts"`
+}
+
+func (s *APIConstShift) String() string {
+	names := make([]string, 0, len(s.Consts))
+	for _, c := range s.Consts {
+		names = append(names, c.Name)
+	}
+	what := "shifted"
+	if s.Reordered {
+		what = "reordered"
+	}
+	return fmt.Sprintf("iota block of `%s` %s: %d constants changed value at once (%s)",
+		s.Type, what, len(s.Consts), strings.Join(names, ", "))
+}
+
+// diffConstValues reports constants, present on both sides, whose value changed.
+func diffConstValues(path string, oldPkg, newPkg *APIPackage) []APIDiffChange {
+	var res []APIDiffChange
+	for name, newVal := range newPkg.ConstValues {
+		oldVal, ok := oldPkg.ConstValues[name]
+		if !ok || oldVal == newVal {
+			continue
+		}
+		res = append(res, APIDiffChange{
+			Label: "Const Values",
+			Path:  path,
+			Name:  name,
+			Old:   name + " = " + oldVal,
+			New:   name + " = " + newVal,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// iotaBlocks maps the exported constants declared in a const ( ... ) block that uses iota
+// to the block, named after its first exported constant.
+func iotaBlocks(files []*ast.File) map[string]string {
+	blocks := make(map[string]string)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			d, ok := decl.(*ast.GenDecl)
+			if !ok || d.Tok != token.CONST || !d.Lparen.IsValid() || !usesIota(d) {
+				continue
+			}
+			block := ""
+			for _, spec := range d.Specs {
+				vs, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for _, name := range vs.Names {
+					if !name.IsExported() {
+						continue
+					}
+					if block == "" {
+						block = name.Name
+					}
+					blocks[name.Name] = block
+				}
+			}
+		}
+	}
+	return blocks
+}
+
+func usesIota(d *ast.GenDecl) bool {
+	found := false
+	ast.Inspect(d, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == "iota" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// detectConstShifts groups value changes by the iota block of the new ref, and calls out blocks
+// where several constants changed at once, which is what an inserted, removed or moved constant
+// looks like. Standalone constants and blocks without iota are never grouped.
+func detectConstShifts(path string, newPkg *APIPackage, changed []APIDiffChange) []APIConstShift {
+	constTypes := make(map[string]string)
+	for _, c := range newPkg.Consts {
+		name := symbolName(c)
+		constTypes[name] = strings.TrimSpace(strings.TrimPrefix(c, name))
+	}
+
+	byBlock := make(map[string][]APIDiffChange)
+	for _, c := range changed {
+		if block, ok := newPkg.IotaBlocks[c.Name]; ok {
+			byBlock[block] = append(byBlock[block], c)
+		}
+	}
+
+	var res []APIConstShift
+	for block, items := range byBlock {
+		if len(items) < 2 {
+			continue
+		}
+		oldVals := make(map[string]int)
+		newVals := make(map[string]int)
+		for _, c := range items {
+			oldVals[strings.TrimPrefix(c.Old, c.Name+" = ")]++
+			newVals[strings.TrimPrefix(c.New, c.Name+" = ")]++
+		}
+		res = append(res, APIConstShift{
+			Path:      path,
+			Block:     block,
+			Type:      constTypes[block],
+			Reordered: sameCounts(oldVals, newVals),
+			Consts:    items,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Block < res[j].Block })
+	return res
+}
+
+func sameCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// compatConstValues classifies value changes: code compiled against the old value
+// (switch cases, persisted or transmitted values) now behaves differently.
+func compatConstValues(changed []APIDiffChange, shifts []APIConstShift) []APICompatChange {
+	shifted := make(map[string]string)
+	for i := range shifts {
+		for _, c := range shifts[i].Consts {
+			shifted[c.Name] = shifts[i].String()
+		}
+	}
+	res := make([]APICompatChange, 0, len(changed))
+	for i := range changed {
+		reason := "constant value changed; code relying on the old value behaves differently"
+		if s, ok := shifted[changed[i].Name]; ok {
+			reason = s + "; stored or transmitted values now map to different constants"
+		}
+		res = append(res, compatChanged(changed[i].Label, &changed[i], false, reason))
+	}
+	return res
+}
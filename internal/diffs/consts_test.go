@@ -0,0 +1,155 @@
+package diffs
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAPI_ConstValues(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Consts: []string{"DefaultTimeout untyped int", "Name untyped string"},
+			ConstValues: map[string]string{
+				"DefaultTimeout": "30",
+				"Name":           `"relimpact"`,
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Consts: []string{"DefaultTimeout untyped int", "Name untyped string"},
+			ConstValues: map[string]string{
+				"DefaultTimeout": "5",
+				"Name":           `"relimpact"`,
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	require.Len(t, apiDiff.ConstValuesChanged, 1)
+	assert.Equal(t, "DefaultTimeout = 30", apiDiff.ConstValuesChanged[0].Old)
+	assert.Equal(t, "DefaultTimeout = 5", apiDiff.ConstValuesChanged[0].New)
+	assert.Empty(t, apiDiff.ConstShifts)
+
+	incompatible := apiDiff.Incompatible()
+	require.Len(t, incompatible, 1)
+	assert.Equal(t, "DefaultTimeout = 30 → DefaultTimeout = 5", incompatible[0].Symbol)
+
+	out := apiDiff.String()
+	assert.Contains(t, out, "## Const Values Changed")
+	assert.Contains(t, out, "`DefaultTimeout = 30` → `DefaultTimeout = 5`")
+}
+
+func TestDiffAPI_ConstShifts(t *testing.T) {
+	consts := []string{"StatusActive pkg/mypkg.Status", "StatusDisabled pkg/mypkg.Status", "StatusPending pkg/mypkg.Status"}
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Consts: consts,
+			ConstValues: map[string]string{
+				"StatusActive":   "0",
+				"StatusDisabled": "1",
+				"StatusPending":  "2",
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Consts: consts,
+			IotaBlocks: map[string]string{
+				"StatusPending":  "StatusPending",
+				"StatusActive":   "StatusPending",
+				"StatusDisabled": "StatusPending",
+			},
+			ConstValues: map[string]string{
+				"StatusPending":  "0",
+				"StatusActive":   "1",
+				"StatusDisabled": "2",
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	require.Len(t, apiDiff.ConstValuesChanged, 3)
+	require.Len(t, apiDiff.ConstShifts, 1)
+
+	shift := apiDiff.ConstShifts[0]
+	assert.Equal(t, "pkg/mypkg.Status", shift.Type)
+	assert.Equal(t, "StatusPending", shift.Block)
+	assert.True(t, shift.Reordered)
+	assert.Equal(t, "iota block of `pkg/mypkg.Status` reordered: 3 constants changed value at once "+
+		"(StatusActive, StatusDisabled, StatusPending)", shift.String())
+
+	for _, c := range apiDiff.Incompatible() {
+		assert.True(t, strings.HasSuffix(c.Reason, "stored or transmitted values now map to different constants"), c.Reason)
+	}
+	assert.Contains(t, apiDiff.String(), "> **Warning:** `pkg/mypkg`: iota block of `pkg/mypkg.Status` reordered")
+}
+
+func TestDetectConstShifts_Inserted(t *testing.T) {
+	newPkg := &APIPackage{
+		Consts:     []string{"A pkg.Level", "B pkg.Level", "C pkg.Level", "Timeout untyped int"},
+		IotaBlocks: map[string]string{"A": "A", "B": "A", "C": "A"},
+	}
+	changed := []APIDiffChange{
+		{Name: "B", Old: "B = 1", New: "B = 2"},
+		{Name: "C", Old: "C = 2", New: "C = 3"},
+		{Name: "Timeout", Old: "Timeout = 30", New: "Timeout = 5"},
+	}
+
+	shifts := detectConstShifts("pkg", newPkg, changed)
+	require.Len(t, shifts, 1)
+	assert.Equal(t, "A", shifts[0].Block)
+	assert.Equal(t, "pkg.Level", shifts[0].Type)
+	assert.False(t, shifts[0].Reordered)
+	assert.Len(t, shifts[0].Consts, 2)
+}
+
+func TestDetectConstShifts_Standalone(t *testing.T) {
+	newPkg := &APIPackage{Consts: []string{"DefaultTimeout untyped int", "MaxRetries untyped int"}}
+	changed := []APIDiffChange{
+		{Name: "DefaultTimeout", Old: "DefaultTimeout = 30", New: "DefaultTimeout = 5"},
+		{Name: "MaxRetries", Old: "MaxRetries = 3", New: "MaxRetries = 10"},
+	}
+	assert.Empty(t, detectConstShifts("pkg", newPkg, changed), "unrelated constants of one type are not an iota block")
+
+	for _, c := range compatConstValues(changed, nil) {
+		assert.NotContains(t, c.Reason, "stored or transmitted values")
+	}
+}
+
+func TestIotaBlocks(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "src.go", `package p
+
+const DefaultTimeout = 30
+
+const MaxRetries = 3
+
+const (
+	_ = iota
+	Low
+	High
+)
+
+const (
+	KB = 1 << (10 * (iota + 1))
+	MB
+)
+
+const (
+	Name    = "p"
+	Version = "1"
+)
+`, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"Low": "Low", "High": "Low",
+		"KB": "KB", "MB": "KB",
+	}, iotaBlocks([]*ast.File{f}))
+}
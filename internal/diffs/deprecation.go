@@ -0,0 +1,102 @@
+package diffs
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// deprecationNotices collects "Deprecated:" paragraphs from the doc comments of exported symbols,
+// keyed like symbolDocs.
+func deprecationNotices(files []*ast.File) map[string]string {
+	notices := make(map[string]string)
+	for key, doc := range symbolDocs(files) {
+		if notice := deprecationNotice(doc); notice != "" {
+			notices[key] = notice
+		}
+	}
+	return notices
+}
+
+// deprecationNotice returns the paragraph of a doc comment that starts with "Deprecated: ", if any.
+func deprecationNotice(doc string) string {
+	for _, para := range strings.Split(doc, "\n\n") {
+		para = strings.TrimSpace(para)
+		if strings.HasPrefix(para, "Deprecated: ") {
+			return strings.Join(strings.Fields(para), " ")
+		}
+	}
+	return ""
+}
+
+// diffDeprecations reports symbols that gained a deprecation notice, and splits removed symbols
+// by whether the old ref had deprecated them. Members of removed types are covered by the type.
+func diffDeprecations(path string, oldPkg, newPkg *APIPackage) (deprecated, removedAfter, removedWithout []APIDiffRes) {
+	oldKeys := make(map[string]bool)
+	for _, key := range symbolKeys(oldPkg) {
+		oldKeys[key] = true
+	}
+	newKeys := make(map[string]bool)
+	for _, key := range symbolKeys(newPkg) {
+		newKeys[key] = true
+	}
+
+	for key, notice := range newPkg.Deprecated {
+		if _, ok := oldPkg.Deprecated[key]; ok {
+			continue
+		}
+		if key == "" || oldKeys[key] && newKeys[key] {
+			deprecated = append(deprecated, deprecationRes("Deprecated", path, key, notice, newPkg.Positions))
+		}
+	}
+
+	for key := range oldKeys {
+		if newKeys[key] {
+			continue
+		}
+		if tname, _, ok := strings.Cut(key, "."); ok && !newKeys[tname] {
+			continue
+		}
+		if notice, ok := oldPkg.Deprecated[key]; ok {
+			removedAfter = append(removedAfter, deprecationRes("Removed", path, key, notice, oldPkg.Positions))
+		} else {
+			removedWithout = append(removedWithout, deprecationRes("Removed", path, key, "", oldPkg.Positions))
+		}
+	}
+	return deprecated, removedAfter, removedWithout
+}
+
+// removedPackageDeprecation classifies a removed package by its old deprecation notice.
+func removedPackageDeprecation(path string, oldPkg *APIPackage) (removedAfter, removedWithout []APIDiffRes) {
+	if notice, ok := oldPkg.Deprecated[""]; ok {
+		return []APIDiffRes{deprecationRes("Removed", path, "", notice, nil)}, nil
+	}
+	return nil, []APIDiffRes{deprecationRes("Removed", path, "", "", nil)}
+}
+
+// deprecationRes describes a symbol by its key, "" for the package, which has no position.
+func deprecationRes(label, path, key, notice string, positions map[string]string) APIDiffRes {
+	x := "`" + key + "`"
+	if key == "" {
+		x = "package `" + path + "`"
+	}
+	if notice != "" {
+		x += ": " + notice
+	}
+	return APIDiffRes{Label: label, Path: path, X: x, Pos: positions[key]}
+}
+
+// writeDeprecationSection renders entries under a heading, grouped by package.
+func writeDeprecationSection(sb *strings.Builder, links *Links, heading string, items []APIDiffRes) {
+	if len(items) == 0 {
+		return
+	}
+	grouped := make(map[string]map[string][]string)
+	for _, r := range items {
+		if _, ok := grouped[r.Path]; !ok {
+			grouped[r.Path] = make(map[string][]string)
+		}
+		grouped[r.Path][r.Label] = append(grouped[r.Path][r.Label], links.withPosition(r.X, r.Pos, r.Label == "Removed"))
+	}
+	sb.WriteString("\n### " + heading + "\n")
+	writeGrouped(sb, grouped)
+}
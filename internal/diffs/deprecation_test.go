@@ -0,0 +1,116 @@
+package diffs
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeprecationNotices(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "src.go", `// Package p does things.
+//
+// Deprecated: use example.com/q instead.
+package p
+
+// Open opens.
+//
+// Deprecated: use OpenFile instead,
+// which takes flags.
+func Open() {}
+
+// OpenFile opens with flags.
+func OpenFile(flags int) {}
+
+// Deprecated: use Client.
+type Conn struct {
+	// Deprecated: use Addr.
+	Host string
+	Addr string
+}
+
+// Deprecated: use Do.
+func (c *Conn) Send() {}
+
+const (
+	// Deprecated: use ModeB.
+	ModeA = iota
+	ModeB
+)
+
+// not a deprecation: Deprecated: must start the paragraph
+var X int
+
+// Deprecated: unexported symbols are not part of the API.
+func helper() {}
+`, parser.ParseComments)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"":          "Deprecated: use example.com/q instead.",
+		"Open":      "Deprecated: use OpenFile instead, which takes flags.",
+		"Conn":      "Deprecated: use Client.",
+		"Conn.Host": "Deprecated: use Addr.",
+		"Conn.Send": "Deprecated: use Do.",
+		"ModeA":     "Deprecated: use ModeB.",
+	}, deprecationNotices([]*ast.File{f}))
+}
+
+func TestDiffAPI_Deprecations(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs: []string{"Open()", "OpenFile(int)", "Close()", "Dial()"},
+			Types: map[string]APIType{
+				"Conn":   {Kind: "struct", Fields: []string{"Host string", "Addr string"}},
+				"Legacy": {Kind: "struct", Fields: []string{"ID int"}},
+			},
+			Deprecated: map[string]string{
+				"Open":      "Deprecated: use OpenFile instead.",
+				"Conn.Host": "Deprecated: use Addr.",
+			},
+		},
+		"pkg/old": {
+			Deprecated: map[string]string{"": "Deprecated: use pkg/mypkg."},
+		},
+		"pkg/gone": {},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs: []string{"OpenFile(int)", "Dial()"},
+			Types: map[string]APIType{
+				"Conn": {Kind: "struct", Fields: []string{"Addr string"}},
+			},
+			Deprecated: map[string]string{
+				"Dial": "Deprecated: use DialContext.",
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+
+	require.Len(t, apiDiff.Deprecated, 1)
+	assert.Equal(t, "`Dial`: Deprecated: use DialContext.", apiDiff.Deprecated[0].X)
+
+	var after, without []string
+	for _, r := range apiDiff.RemovedAfterDeprecation {
+		after = append(after, r.X)
+	}
+	for _, r := range apiDiff.RemovedWithoutDeprecation {
+		without = append(without, r.X)
+	}
+	assert.ElementsMatch(t, []string{
+		"`Open`: Deprecated: use OpenFile instead.",
+		"`Conn.Host`: Deprecated: use Addr.",
+		"package `pkg/old`: Deprecated: use pkg/mypkg.",
+	}, after)
+	// members of the removed type Legacy are covered by the type itself
+	assert.ElementsMatch(t, []string{"`Close`", "`Legacy`", "package `pkg/gone`"}, without)
+
+	out := apiDiff.String()
+	assert.Contains(t, out, "- [Newly Deprecated](#newly-deprecated)")
+	assert.Contains(t, out, "### Removed After Deprecation")
+	assert.Contains(t, out, "### Removed Without Deprecation")
+}
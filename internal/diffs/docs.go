@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -169,7 +170,20 @@ func collectMarkdownFiles(oldDir, newDir string) []string {
 	seen := make(map[string]bool)
 	var files []string
 
+	add := func(rel string) {
+		if strings.HasSuffix(rel, ".md") && !seen[rel] {
+			seen[rel] = true
+			files = append(files, rel)
+		}
+	}
 	walk := func(base string) {
+		// a working tree holds ignored files too, e.g. node_modules or a previous release-impact.md
+		if listed, ok := gitFiles(base); ok {
+			for _, rel := range listed {
+				add(filepath.FromSlash(rel))
+			}
+			return
+		}
 		// TODO: log error
 		//nolint:errcheck
 		_ = filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
@@ -179,14 +193,8 @@ func collectMarkdownFiles(oldDir, newDir string) []string {
 			if d.IsDir() {
 				return nil
 			}
-			if strings.HasSuffix(d.Name(), ".md") {
-				rel, err := filepath.Rel(base, path)
-				if err == nil {
-					if !seen[rel] {
-						seen[rel] = true
-						files = append(files, rel)
-					}
-				}
+			if rel, err := filepath.Rel(base, path); err == nil {
+				add(rel)
 			}
 			return nil
 		})
@@ -199,6 +207,24 @@ func collectMarkdownFiles(oldDir, newDir string) []string {
 	return files
 }
 
+// gitFiles lists the files of the git checkout in dir that are tracked or untracked but not ignored,
+// relative to dir. It returns false when dir is not in a git checkout.
+func gitFiles(dir string) ([]string, bool) {
+	cmd := exec.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, false
+	}
+	var files []string
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, true
+}
+
 func parseDoc(path string) *DocInfo {
 	content, err := os.ReadFile(path)
 	if err != nil {
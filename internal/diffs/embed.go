@@ -0,0 +1,167 @@
+package diffs
+
+import (
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// APIPromoted is a field or method promoted into a struct through an embedded field.
+type APIPromoted struct {
+	Kind   string `json:"kind"`   // "field" or "method"
+	Member string `json:"member"` // "Name Type" or "Name(sig)"
+	Via    string `json:"via"`    // embedding path, e.g. "Base" or "Base.Inner"
+}
+
+func (p APIPromoted) String() string {
+	return fmt.Sprintf("%s %s (via %s)", p.Kind, p.Member, p.Via)
+}
+
+// promotedMembers returns the embedded fields of a struct type and every exported field
+// and method promoted through them, including through embedded pointers.
+func promotedMembers(t types.Type, pkg *types.Package) (embedded []string, promoted []APIPromoted) {
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return nil, nil
+	}
+
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Embedded() {
+			embedded = append(embedded, f.Name()+" "+f.Type().String())
+		}
+	}
+	if len(embedded) == 0 {
+		return nil, nil
+	}
+
+	// fields: collect candidate names from embedded structs, then let the type checker
+	// resolve each one, so that shadowing and ambiguity follow the language rules
+	names := make(map[string]bool)
+	collectEmbeddedFieldNames(st, make(map[types.Type]bool), names)
+	sortedNames := make([]string, 0, len(names))
+	for name := range names {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+
+	for _, name := range sortedNames {
+		obj, index, _ := types.LookupFieldOrMethod(t, true, pkg, name)
+		v, ok := obj.(*types.Var)
+		if !ok || !v.IsField() || len(index) < 2 {
+			continue
+		}
+		promoted = append(promoted, APIPromoted{
+			Kind:   "field",
+			Member: v.Name() + " " + v.Type().String(),
+			Via:    embeddingPath(t, index),
+		})
+	}
+
+	// methods: the method set of *T covers methods promoted through values and pointers
+	ms := types.NewMethodSet(types.NewPointer(t))
+	for i := 0; i < ms.Len(); i++ {
+		sel := ms.At(i)
+		if !sel.Obj().Exported() || len(sel.Index()) < 2 {
+			continue
+		}
+		//nolint:errcheck
+		sig := sel.Obj().Type().(*types.Signature)
+		promoted = append(promoted, APIPromoted{
+			Kind:   "method",
+			Member: sel.Obj().Name() + signatureString(sig),
+			Via:    embeddingPath(t, sel.Index()),
+		})
+	}
+
+	return embedded, promoted
+}
+
+func collectEmbeddedFieldNames(st *types.Struct, seen map[types.Type]bool, names map[string]bool) {
+	for i := 0; i < st.NumFields(); i++ {
+		f := st.Field(i)
+		if !f.Embedded() {
+			continue
+		}
+		et := deref(f.Type())
+		if seen[et] {
+			continue
+		}
+		seen[et] = true
+		inner, ok := et.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for j := 0; j < inner.NumFields(); j++ {
+			if inner.Field(j).Exported() {
+				names[inner.Field(j).Name()] = true
+			}
+		}
+		collectEmbeddedFieldNames(inner, seen, names)
+	}
+}
+
+// embeddingPath names the embedded fields walked by a selection index (all but the last step).
+func embeddingPath(t types.Type, index []int) string {
+	var path []string
+	cur := t
+	for _, idx := range index[:len(index)-1] {
+		st, ok := deref(cur).Underlying().(*types.Struct)
+		if !ok {
+			break
+		}
+		f := st.Field(idx)
+		path = append(path, f.Name())
+		cur = f.Type()
+	}
+	return strings.Join(path, ".")
+}
+
+func deref(t types.Type) types.Type {
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
+
+// diffPromoted reports promoted members that appeared or disappeared, each naming the embedding it came through.
+func diffPromoted(label, path string, oldList, newList []APIPromoted) (added, removed []APIDiffRes) {
+	key := func(p APIPromoted) string { return p.Kind + " " + p.Member }
+	oldSet := make(map[string]APIPromoted)
+	for _, p := range oldList {
+		oldSet[key(p)] = p
+	}
+	newSet := make(map[string]APIPromoted)
+	for _, p := range newList {
+		newSet[key(p)] = p
+	}
+
+	for k, p := range newSet {
+		if _, ok := oldSet[k]; !ok {
+			added = append(added, APIDiffRes{Label: label, Path: path, X: p.String()})
+		}
+	}
+	for k, p := range oldSet {
+		if _, ok := newSet[k]; !ok {
+			removed = append(removed, APIDiffRes{Label: label, Path: path, X: p.String()})
+		}
+	}
+	return added, removed
+}
+
+// withoutPromoted drops method entries that are promoted through an embedded field.
+func withoutPromoted(methods []APIDiffRes, promoted []APIPromoted) []APIDiffRes {
+	skip := make(map[string]bool)
+	for _, p := range promoted {
+		if p.Kind == "method" {
+			skip[p.Member] = true
+		}
+	}
+	var res []APIDiffRes
+	for _, m := range methods {
+		if !skip[m.X] {
+			res = append(res, m)
+		}
+	}
+	return res
+}
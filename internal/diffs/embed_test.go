@@ -0,0 +1,101 @@
+package diffs
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// typeCheck type-checks a single self-contained source file.
+func typeCheck(t *testing.T, src string) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "src.go", src, parser.ParseComments)
+	require.NoError(t, err)
+	pkg, err := (&types.Config{}).Check("example.com/p", fset, []*ast.File{f}, nil)
+	require.NoError(t, err)
+	return pkg
+}
+
+func TestPromotedMembers(t *testing.T) {
+	pkg := typeCheck(t, `package p
+
+type Inner struct{ Deep int }
+
+func (Inner) Ping() {}
+
+type Base struct {
+	*Inner
+	ID   int
+	name string
+}
+
+func (*Base) Close() error { return nil }
+
+type T struct {
+	Base
+	ID string // shadows Base.ID
+}
+`)
+	obj := pkg.Scope().Lookup("T")
+	embedded, promoted := promotedMembers(obj.Type(), pkg)
+
+	assert.Equal(t, []string{"Base example.com/p.Base"}, embedded)
+	assert.ElementsMatch(t, []APIPromoted{
+		{Kind: "field", Member: "Deep int", Via: "Base.Inner"},
+		{Kind: "field", Member: "Inner *example.com/p.Inner", Via: "Base"},
+		{Kind: "method", Member: "Close() -> (error)", Via: "Base"},
+		{Kind: "method", Member: "Ping()", Via: "Base.Inner"},
+	}, promoted)
+}
+
+func TestDiffAPI_Promoted(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Types: map[string]APIType{
+				"Client": {
+					Kind:     "struct",
+					Fields:   []string{"Base pkg/mypkg.Base"},
+					Methods:  []string{"Close() -> (error)"},
+					Embedded: []string{"Base pkg/mypkg.Base"},
+					Promoted: []APIPromoted{
+						{Kind: "field", Member: "ID int", Via: "Base"},
+						{Kind: "method", Member: "Close() -> (error)", Via: "Base"},
+					},
+				},
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Types: map[string]APIType{
+				"Client": {Kind: "struct"},
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+
+	require.Len(t, apiDiff.EmbeddedRemoved, 1)
+	assert.Equal(t, "Base pkg/mypkg.Base", apiDiff.EmbeddedRemoved[0].X)
+
+	var removed []string
+	for _, r := range apiDiff.PromotedRemoved {
+		removed = append(removed, r.X)
+	}
+	assert.ElementsMatch(t, []string{"field ID int (via Base)", "method Close() -> (error) (via Base)"}, removed)
+
+	// the promoted method is reported once, with the embedding that caused it
+	var closeBreaks int
+	for _, c := range apiDiff.Incompatible() {
+		if c.Symbol == "Close() -> (error)" || c.Symbol == "method Close() -> (error) (via Base)" {
+			closeBreaks++
+		}
+	}
+	assert.Equal(t, 1, closeBreaks)
+}
@@ -0,0 +1,224 @@
+package diffs
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DependencyExposure is a dependency whose version changed while exported API exposes its types.
+type DependencyExposure struct {
+	Module     string   `json:"module"`
+	OldVersion string   `json:"old_version,omitempty"` // empty when the dependency was added
+	NewVersion string   `json:"new_version,omitempty"` // empty when the dependency was removed
+	Symbols    []string `json:"symbols"`               // "pkg.Name" or "pkg.Type.Name"
+}
+
+func (e DependencyExposure) String() string {
+	change := e.OldVersion + " → " + e.NewVersion
+	switch {
+	case e.OldVersion == "":
+		change = "was added at " + e.NewVersion
+	case e.NewVersion == "":
+		change = "was removed (was " + e.OldVersion + ")"
+	default:
+		change = "changed " + change
+	}
+	return fmt.Sprintf("API exposes types from dependency `%s`, which %s", e.Module, change)
+}
+
+// exposedPackages records, for every exported func, var, field and method of a package (keyed like symbolKeys),
+// the third-party packages that appear in its type. Standard library packages and the module's own packages are skipped.
+func exposedPackages(pkg *types.Package, modulePath string) map[string][]string {
+	exposes := make(map[string][]string)
+	record := func(key string, t types.Type) {
+		seen := make(map[string]bool)
+		collectPackages(t, seen, make(map[types.Type]bool))
+		var paths []string
+		for path := range seen {
+			if isThirdParty(path, modulePath) {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) > 0 {
+			sort.Strings(paths)
+			exposes[key] = paths
+		}
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		if !token.IsExported(name) {
+			continue
+		}
+		switch o := scope.Lookup(name).(type) {
+		case *types.Func, *types.Var:
+			record(name, o.Type())
+		case *types.TypeName:
+			if o.IsAlias() {
+				record(name, types.Unalias(o.Type()))
+				continue
+			}
+			switch u := o.Type().Underlying().(type) {
+			case *types.Struct:
+				for i := 0; i < u.NumFields(); i++ {
+					if f := u.Field(i); f.Exported() {
+						record(name+"."+f.Name(), f.Type())
+					}
+				}
+			case *types.Interface:
+				for i := 0; i < u.NumMethods(); i++ {
+					if m := u.Method(i); m.Exported() {
+						record(name+"."+m.Name(), m.Type())
+					}
+				}
+				continue
+			default:
+				record(name, u)
+			}
+			methods, _ := methodSets(o.Type())
+			for _, m := range methods {
+				record(name+"."+m.Name(), m.Type())
+			}
+		}
+	}
+	return exposes
+}
+
+// collectPackages adds the packages of every named type reachable from t to seen.
+func collectPackages(t types.Type, seen map[string]bool, visited map[types.Type]bool) {
+	if t == nil || visited[t] {
+		return
+	}
+	visited[t] = true
+
+	switch t := t.(type) {
+	case *types.Alias:
+		collectPackages(types.Unalias(t), seen, visited)
+	case *types.Named:
+		if pkg := t.Obj().Pkg(); pkg != nil {
+			seen[pkg.Path()] = true
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			collectPackages(t.TypeArgs().At(i), seen, visited)
+		}
+	case *types.Pointer:
+		collectPackages(t.Elem(), seen, visited)
+	case *types.Slice:
+		collectPackages(t.Elem(), seen, visited)
+	case *types.Array:
+		collectPackages(t.Elem(), seen, visited)
+	case *types.Chan:
+		collectPackages(t.Elem(), seen, visited)
+	case *types.Map:
+		collectPackages(t.Key(), seen, visited)
+		collectPackages(t.Elem(), seen, visited)
+	case *types.Signature:
+		for _, tuple := range []*types.Tuple{t.Params(), t.Results()} {
+			for i := 0; i < tuple.Len(); i++ {
+				collectPackages(tuple.At(i).Type(), seen, visited)
+			}
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			collectPackages(t.Field(i).Type(), seen, visited)
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumMethods(); i++ {
+			collectPackages(t.Method(i).Type(), seen, visited)
+		}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			collectPackages(t.EmbeddedType(i), seen, visited)
+		}
+	case *types.TypeParam:
+		collectPackages(t.Constraint(), seen, visited)
+	case *types.Union:
+		for i := 0; i < t.Len(); i++ {
+			collectPackages(t.Term(i).Type(), seen, visited)
+		}
+	}
+}
+
+// isThirdParty reports whether a package path belongs neither to the standard library nor to the module.
+func isThirdParty(path, modulePath string) bool {
+	if path == modulePath || strings.HasPrefix(path, modulePath+"/") {
+		return false
+	}
+	first, _, _ := strings.Cut(path, "/")
+	return strings.Contains(first, ".")
+}
+
+// requiredVersions maps the modules required by the go.mod in dir to their versions.
+func requiredVersions(dir string) map[string]string {
+	versions := make(map[string]string)
+	for _, r := range parseGoMod(filepath.Join(dir, "go.mod")).Require {
+		versions[r.Mod.Path] = r.Mod.Version
+	}
+	return versions
+}
+
+// owningModule returns the required module that provides a package, i.e. the longest module path prefix.
+func owningModule(pkgPath string, versions map[string]string) string {
+	owner := ""
+	for mod := range versions {
+		if (pkgPath == mod || strings.HasPrefix(pkgPath, mod+"/")) && len(mod) > len(owner) {
+			owner = mod
+		}
+	}
+	return owner
+}
+
+// DiffExposedDependencies reports dependencies that were updated, added or removed between the go.mod files
+// of oldDir and newDir while exported API exposes their types. Added and updated dependencies are matched
+// against the new API, removed ones against the old API.
+func DiffExposedDependencies(oldAPI, newAPI map[string]APIPackage, oldDir, newDir string) []DependencyExposure {
+	oldVersions := requiredVersions(oldDir)
+	newVersions := requiredVersions(newDir)
+
+	// module -> exposing symbols
+	exposers := func(api map[string]APIPackage, versions map[string]string) map[string][]string {
+		res := make(map[string][]string)
+		for path, pkg := range api {
+			for key, pkgPaths := range pkg.Exposes {
+				mods := make(map[string]bool)
+				for _, p := range pkgPaths {
+					if mod := owningModule(p, versions); mod != "" {
+						mods[mod] = true
+					}
+				}
+				for mod := range mods {
+					res[mod] = append(res[mod], path+"."+key)
+				}
+			}
+		}
+		return res
+	}
+	oldExposers := exposers(oldAPI, oldVersions)
+	newExposers := exposers(newAPI, newVersions)
+
+	var res []DependencyExposure
+	for mod, newVer := range newVersions {
+		oldVer, ok := oldVersions[mod]
+		if ok && oldVer == newVer {
+			continue
+		}
+		if symbols := newExposers[mod]; len(symbols) > 0 {
+			sort.Strings(symbols)
+			res = append(res, DependencyExposure{Module: mod, OldVersion: oldVer, NewVersion: newVer, Symbols: symbols})
+		}
+	}
+	for mod, oldVer := range oldVersions {
+		if _, ok := newVersions[mod]; ok {
+			continue
+		}
+		if symbols := oldExposers[mod]; len(symbols) > 0 {
+			sort.Strings(symbols)
+			res = append(res, DependencyExposure{Module: mod, OldVersion: oldVer, Symbols: symbols})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Module < res[j].Module })
+	return res
+}
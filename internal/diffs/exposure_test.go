@@ -0,0 +1,146 @@
+package diffs
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// typeCheckWithDep checks src as example.com/p, which may import the third-party package github.com/dep/x.
+func typeCheckWithDep(t *testing.T, src string) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	depFile, err := parser.ParseFile(fset, "dep.go", `package x
+
+type Client struct{}
+type Option func(*Client)
+`, 0)
+	require.NoError(t, err)
+	dep, err := (&types.Config{}).Check("github.com/dep/x", fset, []*ast.File{depFile}, nil)
+	require.NoError(t, err)
+
+	f, err := parser.ParseFile(fset, "src.go", src, 0)
+	require.NoError(t, err)
+	imp := importerFunc(func(path string) (*types.Package, error) {
+		if path == dep.Path() {
+			return dep, nil
+		}
+		return importer.Default().Import(path)
+	})
+	pkg, err := (&types.Config{Importer: imp}).Check("example.com/p", fset, []*ast.File{f}, nil)
+	require.NoError(t, err)
+	return pkg
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+func TestExposedPackages(t *testing.T) {
+	pkg := typeCheckWithDep(t, `package p
+
+import "github.com/dep/x"
+
+type Local struct{}
+
+type Service struct {
+	Client *x.Client
+	Opts   []x.Option
+	Name   string
+	local  *x.Client
+}
+
+func (s *Service) Use(c map[string]x.Client) {}
+func (s *Service) Local() Local             { return Local{} }
+
+type Getter interface {
+	Get() (*x.Client, error)
+}
+
+type Opt = x.Option
+
+func New(opts ...x.Option) *Service { return nil }
+func Plain(n int) Local             { return Local{} }
+
+var Default x.Client
+`)
+
+	exposes := exposedPackages(pkg, "example.com/p")
+	dep := []string{"github.com/dep/x"}
+	assert.Equal(t, map[string][]string{
+		"Service.Client": dep,
+		"Service.Opts":   dep,
+		"Service.Use":    dep,
+		"Getter.Get":     dep,
+		"Opt":            dep,
+		"New":            dep,
+		"Default":        dep,
+	}, exposes)
+}
+
+func TestIsThirdParty(t *testing.T) {
+	assert.True(t, isThirdParty("github.com/dep/x", "example.com/m"))
+	assert.False(t, isThirdParty("net/http", "example.com/m"))
+	assert.False(t, isThirdParty("example.com/m/api", "example.com/m"))
+	assert.True(t, isThirdParty("example.com/mx", "example.com/m"))
+}
+
+func TestDiffExposedDependencies(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	writeGoMod(t, oldDir, `module example.com/m
+
+go 1.22
+
+require (
+	github.com/dep/x v1.0.0
+	github.com/dep/gone v0.1.0
+	github.com/other/y v1.0.0
+)
+`)
+	writeGoMod(t, newDir, `module example.com/m
+
+go 1.22
+
+require (
+	github.com/dep/x v1.2.0
+	github.com/dep/new v0.3.0
+	github.com/other/y v1.1.0
+)
+`)
+	oldAPI := map[string]APIPackage{
+		"example.com/m/api": {Exposes: map[string][]string{"Legacy": {"github.com/dep/gone/sub"}}},
+	}
+	newAPI := map[string]APIPackage{
+		"example.com/m/api": {Exposes: map[string][]string{
+			"New":            {"github.com/dep/x"},
+			"Service.Client": {"github.com/dep/x/client"},
+			"Service.Store":  {"github.com/dep/new"},
+		}},
+	}
+
+	got := DiffExposedDependencies(oldAPI, newAPI, oldDir, newDir)
+	require.Len(t, got, 3)
+	assert.Equal(t, DependencyExposure{
+		Module: "github.com/dep/gone", OldVersion: "v0.1.0", Symbols: []string{"example.com/m/api.Legacy"},
+	}, got[0])
+	assert.Equal(t, DependencyExposure{
+		Module: "github.com/dep/new", NewVersion: "v0.3.0", Symbols: []string{"example.com/m/api.Service.Store"},
+	}, got[1])
+	assert.Equal(t, DependencyExposure{
+		Module: "github.com/dep/x", OldVersion: "v1.0.0", NewVersion: "v1.2.0",
+		Symbols: []string{"example.com/m/api.New", "example.com/m/api.Service.Client"},
+	}, got[2])
+
+	assert.Equal(t, "API exposes types from dependency `github.com/dep/x`, which changed v1.0.0 → v1.2.0", got[2].String())
+	assert.Equal(t, "API exposes types from dependency `github.com/dep/new`, which was added at v0.3.0", got[1].String())
+
+	d := GoModDiff{Exposed: got}
+	assert.Contains(t, d.String(), "### Exposed dependencies changed\n- API exposes types from dependency `github.com/dep/gone`")
+	assert.Contains(t, d.String(), "  - `example.com/m/api.Service.Client`\n")
+}
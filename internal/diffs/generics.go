@@ -0,0 +1,221 @@
+package diffs
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// APITypeParam is a type parameter of a generic func or type.
+type APITypeParam struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint"`
+}
+
+func typeParamList(tps *types.TypeParamList) []APITypeParam {
+	if tps == nil || tps.Len() == 0 {
+		return nil
+	}
+	res := make([]APITypeParam, 0, tps.Len())
+	for i := 0; i < tps.Len(); i++ {
+		tp := tps.At(i)
+		res = append(res, APITypeParam{Name: tp.Obj().Name(), Constraint: tp.Constraint().String()})
+	}
+	return res
+}
+
+// positionalTypeArgs returns n type parameters named by position, $0, $1, ..., to instantiate a generic
+// func or type with, so that its entries do not depend on the names its type parameters are written with.
+func positionalTypeArgs(n int) []types.Type {
+	anyType := types.Universe.Lookup("any").Type()
+	args := make([]types.Type, 0, n)
+	for i := 0; i < n; i++ {
+		args = append(args, types.NewTypeParam(types.NewTypeName(token.NoPos, nil, fmt.Sprintf("$%d", i), nil), anyType))
+	}
+	return args
+}
+
+// recordGenericFunc records the entry of a generic func with positional type parameter names.
+func (p *APIPackage) recordGenericFunc(name string, sig *types.Signature) {
+	inst, err := types.Instantiate(nil, sig, positionalTypeArgs(sig.TypeParams().Len()), false)
+	if err != nil {
+		return
+	}
+	//nolint:errcheck
+	p.setCanonical(name, name+signatureString(inst.(*types.Signature)))
+}
+
+// recordGenericType records the underlying type, fields and methods of a generic type with positional
+// type parameter names. Methods may name the type parameters of their receiver differently from the type.
+func (p *APIPackage) recordGenericType(tname string, named *types.Named) {
+	inst, err := types.Instantiate(nil, named, positionalTypeArgs(named.TypeParams().Len()), false)
+	if err != nil {
+		return
+	}
+	p.setCanonical(tname, inst.Underlying().String())
+	switch ut := inst.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < ut.NumFields(); i++ {
+			if f := ut.Field(i); f.Exported() {
+				p.setCanonical(tname+"."+f.Name(), f.Name()+" "+f.Type().String())
+			}
+		}
+	case *types.Interface:
+		for i := 0; i < ut.NumMethods(); i++ {
+			m := ut.Method(i)
+			//nolint:errcheck
+			p.setCanonical(tname+"."+m.Name(), m.Name()+signatureString(m.Type().(*types.Signature)))
+		}
+		return
+	}
+	methods, _ := methodSets(inst)
+	for _, m := range methods {
+		//nolint:errcheck
+		p.setCanonical(tname+"."+m.Name(), m.Name()+signatureString(m.Type().(*types.Signature)))
+	}
+}
+
+func (p *APIPackage) setCanonical(key, entry string) {
+	if p.Canonical == nil {
+		p.Canonical = make(map[string]string)
+	}
+	p.Canonical[key] = entry
+}
+
+// withoutTypeParamRenames drops changed entries that only differ in the names of type parameters,
+// e.g. G(T) -> (T) and G(K) -> (K). Members of type tname are looked up as "Type.Name".
+func withoutTypeParamRenames(changed []APIDiffChange, oldPkg, newPkg *APIPackage, tname string) []APIDiffChange {
+	res := changed[:0]
+	for _, c := range changed {
+		key := memberKey(tname, c.Name)
+		oldEntry, okOld := oldPkg.Canonical[key]
+		newEntry, okNew := newPkg.Canonical[key]
+		if okOld && okNew && oldEntry == newEntry {
+			continue
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// typeParamsString renders type parameters the way they are declared, e.g. "[K comparable, V any]".
+func typeParamsString(tps []APITypeParam) string {
+	if len(tps) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(tps))
+	for _, tp := range tps {
+		parts = append(parts, tp.Name+" "+tp.Constraint)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// diffTypeParams reports symbols whose type parameter count or constraints changed.
+// Renaming a type parameter alone is not a change.
+func diffTypeParams(label, path string, oldTPs, newTPs map[string][]APITypeParam, exists func(name string) bool) []APIDiffChange {
+	var res []APIDiffChange
+	names := make(map[string]bool)
+	for name := range oldTPs {
+		names[name] = true
+	}
+	for name := range newTPs {
+		names[name] = true
+	}
+	for name := range names {
+		if !exists(name) {
+			continue
+		}
+		o, n := oldTPs[name], newTPs[name]
+		if sameConstraints(o, n) {
+			continue
+		}
+		res = append(res, APIDiffChange{
+			Label: label,
+			Path:  path,
+			Name:  name,
+			Old:   name + typeParamsString(o),
+			New:   name + typeParamsString(n),
+		})
+	}
+	return res
+}
+
+func sameConstraints(a, b []APITypeParam) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Constraint != b[i].Constraint {
+			return false
+		}
+	}
+	return true
+}
+
+// compatTypeParams classifies type parameter changes: adding or removing type parameters
+// breaks explicit instantiations, tightening a constraint breaks existing type arguments,
+// and loosening one (e.g. comparable -> any, or adding union terms) is compatible.
+func compatTypeParams(oldTPs, newTPs map[string][]APITypeParam, changed []APIDiffChange) []APICompatChange {
+	res := make([]APICompatChange, 0, len(changed))
+	for i := range changed {
+		c := &changed[i]
+		o, n := oldTPs[c.Name], newTPs[c.Name]
+		ok, reason := compatConstraints(o, n)
+		res = append(res, compatChanged(c.Label, c, ok, reason))
+	}
+	return res
+}
+
+func compatConstraints(oldTPs, newTPs []APITypeParam) (compatible bool, reason string) {
+	if len(oldTPs) != len(newTPs) {
+		return false, fmt.Sprintf("number of type parameters changed from %d to %d; explicit instantiations no longer compile",
+			len(oldTPs), len(newTPs))
+	}
+	var loosened []string
+	for i := range oldTPs {
+		o, n := oldTPs[i].Constraint, newTPs[i].Constraint
+		if o == n {
+			continue
+		}
+		if !loosens(o, n) {
+			return false, fmt.Sprintf("constraint of %s changed from %s to %s; existing type arguments may no longer satisfy it",
+				newTPs[i].Name, o, n)
+		}
+		loosened = append(loosened, fmt.Sprintf("%s: %s -> %s", newTPs[i].Name, o, n))
+	}
+	return true, "constraints loosened: " + strings.Join(loosened, ", ")
+}
+
+// loosens reports whether every type satisfying oldC also satisfies newC.
+func loosens(oldC, newC string) bool {
+	if newC == "any" || newC == "interface{}" {
+		return true
+	}
+	if oldC == "any" || oldC == "interface{}" {
+		return false
+	}
+	// union constraints: adding terms loosens
+	newTerms := make(map[string]bool)
+	for _, t := range unionTerms(newC) {
+		newTerms[t] = true
+	}
+	for _, t := range unionTerms(oldC) {
+		if !newTerms[t] && !newTerms["~"+strings.TrimPrefix(t, "~")] {
+			return false
+		}
+	}
+	return true
+}
+
+// unionTerms splits a constraint like "~int | string" (optionally wrapped in interface{...}) into its terms.
+func unionTerms(c string) []string {
+	if inner, ok := strings.CutPrefix(c, "interface{"); ok {
+		c = strings.TrimSuffix(inner, "}")
+	}
+	terms := strings.Split(c, "|")
+	for i := range terms {
+		terms[i] = strings.TrimSpace(terms[i])
+	}
+	return terms
+}
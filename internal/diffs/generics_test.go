@@ -0,0 +1,150 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompatConstraints(t *testing.T) {
+	tp := func(constraints ...string) []APITypeParam {
+		res := make([]APITypeParam, 0, len(constraints))
+		for i, c := range constraints {
+			res = append(res, APITypeParam{Name: string(rune('T' + i)), Constraint: c})
+		}
+		return res
+	}
+
+	tests := []struct {
+		name       string
+		oldTPs     []APITypeParam
+		newTPs     []APITypeParam
+		compatible bool
+	}{
+		{"any to comparable", tp("any"), tp("comparable"), false},
+		{"comparable to any", tp("comparable"), tp("any"), true},
+		{"union gains a term", tp("~int | ~string"), tp("~int | ~string | float64"), true},
+		{"union loses a term", tp("~int | ~string"), tp("~int"), false},
+		{"exact to approximate", tp("int"), tp("~int"), true},
+		{"type param added", tp("any"), tp("any", "any"), false},
+		{"generic from plain", nil, tp("any"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, reason := compatConstraints(tt.oldTPs, tt.newTPs)
+			assert.Equal(t, tt.compatible, ok, reason)
+		})
+	}
+}
+
+func TestDiffAPI_TypeParams(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs:      []string{"Map(S) -> (S)", "Keys(M) -> ([]K)"},
+			TypeParams: map[string][]APITypeParam{"Map": {{Name: "S", Constraint: "any"}}, "Keys": {{Name: "M", Constraint: "any"}}},
+			Types: map[string]APIType{
+				"Set": {Kind: "map", TypeParams: []APITypeParam{{Name: "T", Constraint: "comparable"}}},
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs:      []string{"Map(E) -> (E)", "Keys(M) -> ([]K)"},
+			TypeParams: map[string][]APITypeParam{"Map": {{Name: "E", Constraint: "any"}}, "Keys": {{Name: "M", Constraint: "comparable"}}},
+			Types: map[string]APIType{
+				"Set": {Kind: "map", TypeParams: []APITypeParam{{Name: "T", Constraint: "any"}}},
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+
+	require.Len(t, apiDiff.TypeParamsChanged, 2)
+	byName := make(map[string]APIDiffChange)
+	for _, c := range apiDiff.TypeParamsChanged {
+		byName[c.Name] = c
+	}
+	assert.Equal(t, "Keys[M any]", byName["Keys"].Old)
+	assert.Equal(t, "Keys[M comparable]", byName["Keys"].New)
+	assert.Equal(t, "Set[T any]", byName["Set"].New)
+
+	for _, c := range apiDiff.Compat {
+		switch c.Symbol {
+		case "Keys[M any] → Keys[M comparable]":
+			assert.False(t, c.Compatible)
+		case "Set[T comparable] → Set[T any]":
+			assert.True(t, c.Compatible)
+		}
+	}
+}
+
+func TestDiffAPI_TypeParamRenamed(t *testing.T) {
+	oldAPI := snapshotSource(t, `package p
+
+func G[T any](v T) T { return v }
+
+func Keys[M ~map[K]V, K comparable, V any](m M) []K { return nil }
+
+type List[E any] struct {
+	Items []E
+	Next  *List[E]
+}
+
+func (l *List[E]) Push(v E) {}
+
+type Getter[T any] interface{ Get() T }
+
+type Pair[A, B any] [2]A
+`)
+	newAPI := snapshotSource(t, `package p
+
+func G[K any](v K) K { return v }
+
+func Keys[M ~map[K]V, K comparable, V any](m M) []K { return nil }
+
+type List[T any] struct {
+	Items []T
+	Next  *List[T]
+}
+
+func (list *List[Elem]) Push(v Elem) {}
+
+type Getter[V any] interface{ Get() V }
+
+type Pair[X, Y any] [2]X
+`)
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	assert.Empty(t, apiDiff.FuncsChanged)
+	assert.Empty(t, apiDiff.FieldsChanged)
+	assert.Empty(t, apiDiff.MethodsChanged)
+	assert.Empty(t, apiDiff.TypesUnderlyingChanged)
+	assert.Empty(t, apiDiff.TypeParamsChanged)
+	assert.Empty(t, apiDiff.Compat)
+
+	// a real change is still reported, with the written names
+	newAPI = snapshotSource(t, `package p
+
+func G[K any](v K) string { return "" }
+
+func Keys[M ~map[K]V, K comparable, V any](m M) []K { return nil }
+
+type List[E any] struct {
+	Items []E
+	Next  *List[E]
+}
+
+func (l *List[E]) Push(v E) {}
+
+type Getter[T any] interface{ Get() T }
+
+type Pair[A, B any] [2]B
+`)
+	apiDiff = DiffAPI(oldAPI, newAPI)
+	require.Len(t, apiDiff.FuncsChanged, 1)
+	assert.Equal(t, "G(T) -> (T)", apiDiff.FuncsChanged[0].Old)
+	assert.Equal(t, "G(K) -> (string)", apiDiff.FuncsChanged[0].New)
+	require.Len(t, apiDiff.TypesUnderlyingChanged, 1)
+	assert.Equal(t, "Pair", apiDiff.TypesUnderlyingChanged[0].Name)
+}
@@ -0,0 +1,172 @@
+package diffs
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// WellKnownInterface is an interface outside the module, matched by method signatures,
+// since its package is not necessarily loaded. Methods are rendered like APIType.Methods.
+type WellKnownInterface struct {
+	Name    string   `json:"name"`
+	Methods []string `json:"methods"`
+}
+
+// DefaultInterfaces are the standard library interfaces checked when none are configured.
+var DefaultInterfaces = []WellKnownInterface{
+	{"error", []string{"Error() -> (string)"}},
+	{"fmt.Stringer", []string{"String() -> (string)"}},
+	{"fmt.GoStringer", []string{"GoString() -> (string)"}},
+	{"io.Reader", []string{"Read([]byte) -> (int, error)"}},
+	{"io.Writer", []string{"Write([]byte) -> (int, error)"}},
+	{"io.Closer", []string{"Close() -> (error)"}},
+	{"io.ReaderAt", []string{"ReadAt([]byte, int64) -> (int, error)"}},
+	{"io.WriterTo", []string{"WriteTo(io.Writer) -> (int64, error)"}},
+	{"io.ReaderFrom", []string{"ReadFrom(io.Reader) -> (int64, error)"}},
+	{"io.Seeker", []string{"Seek(int64, int) -> (int64, error)"}},
+	{"sort.Interface", []string{"Len() -> (int)", "Less(int, int) -> (bool)", "Swap(int, int)"}},
+	{"encoding.TextMarshaler", []string{"MarshalText() -> ([]byte, error)"}},
+	{"encoding.TextUnmarshaler", []string{"UnmarshalText([]byte) -> (error)"}},
+	{"encoding.BinaryMarshaler", []string{"MarshalBinary() -> ([]byte, error)"}},
+	{"encoding.BinaryUnmarshaler", []string{"UnmarshalBinary([]byte) -> (error)"}},
+	{"encoding/json.Marshaler", []string{"MarshalJSON() -> ([]byte, error)"}},
+	{"encoding/json.Unmarshaler", []string{"UnmarshalJSON([]byte) -> (error)"}},
+	{"database/sql.Scanner", []string{"Scan(any) -> (error)"}},
+	{"database/sql/driver.Valuer", []string{"Value() -> (database/sql/driver.Value, error)"}},
+	{"net/http.Handler", []string{"ServeHTTP(net/http.ResponseWriter, *net/http.Request)"}},
+}
+
+// ParseInterface parses an interface to check: the name of a default one, e.g. "io.Reader",
+// or a definition "name=Method1(sig) -> (res);Method2(sig)".
+func ParseInterface(s string) (WellKnownInterface, error) {
+	name, methods, ok := strings.Cut(s, "=")
+	if !ok {
+		for _, iface := range DefaultInterfaces {
+			if iface.Name == s {
+				return iface, nil
+			}
+		}
+		return WellKnownInterface{}, fmt.Errorf("unknown interface %q, expected one of the defaults or name=Method(sig);", s)
+	}
+	iface := WellKnownInterface{Name: strings.TrimSpace(name)}
+	for _, m := range strings.Split(methods, ";") {
+		if m = strings.TrimSpace(m); m != "" {
+			iface.Methods = append(iface.Methods, m)
+		}
+	}
+	if iface.Name == "" || len(iface.Methods) == 0 {
+		return WellKnownInterface{}, fmt.Errorf("invalid interface %q, expected name=Method(sig);", s)
+	}
+	return iface, nil
+}
+
+// interfacesKey identifies a configured interface set in cache file names.
+func interfacesKey(ifaces []WellKnownInterface) string {
+	parts := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		parts = append(parts, iface.Name+"="+strings.Join(iface.Methods, ";"))
+	}
+	return shortHash(strings.Join(parts, "\n"))
+}
+
+// moduleInterface is an exported interface of the module, keyed "pkgpath.Name".
+type moduleInterface struct {
+	name  string
+	iface *types.Interface
+}
+
+// moduleInterfaces collects the exported, non-generic, non-empty interfaces of the given packages.
+func moduleInterfaces(pkgs []*packages.Package) []moduleInterface {
+	var res []moduleInterface
+	for _, pkg := range pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			o, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !token.IsExported(name) || o.IsAlias() {
+				continue
+			}
+			named, ok := o.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 {
+				continue
+			}
+			if iface, ok := named.Underlying().(*types.Interface); ok && iface.NumMethods() > 0 {
+				res = append(res, moduleInterface{name: pkg.PkgPath + "." + name, iface: iface})
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].name < res[j].name })
+	return res
+}
+
+// implementedInterfaces lists the interfaces that a type or a pointer to it implements:
+// module interfaces checked by the type checker, well-known ones by the method signatures in atype.
+func implementedInterfaces(o *types.TypeName, atype *APIType, ifaces []moduleInterface, wellKnown []WellKnownInterface) []string {
+	if o.IsAlias() || atype.Kind == "interface" {
+		return nil
+	}
+	if named, ok := o.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+		return nil
+	}
+
+	var res []string
+	ptr := types.NewPointer(o.Type())
+	for _, mi := range ifaces {
+		if types.Implements(o.Type(), mi.iface) || types.Implements(ptr, mi.iface) {
+			res = append(res, mi.name)
+		}
+	}
+	for _, iface := range wellKnown {
+		if hasMethods(atype.Methods, iface.Methods) {
+			res = append(res, iface.Name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func hasMethods(have, want []string) bool {
+	normalize := strings.NewReplacer("interface{}", "any").Replace
+	set := make(map[string]bool, len(have))
+	for _, m := range have {
+		set[normalize(m)] = true
+	}
+	for _, m := range want {
+		if !set[normalize(m)] {
+			return false
+		}
+	}
+	return true
+}
+
+// diffImplements reports interfaces a type stopped or started implementing.
+func diffImplements(path, tname string, oldType, newType *APIType) (added, removed []APIDiffRes) {
+	return diffList(fmt.Sprintf("Type `%s` Implements", tname), path, oldType.Implements, newType.Implements)
+}
+
+// writeImplementsSection lists interfaces types stopped and started implementing, per package.
+func writeImplementsSection(sb *strings.Builder, links *Links, added, removed []APIDiffRes) {
+	if len(added)+len(removed) == 0 {
+		return
+	}
+	var lines []string
+	for _, x := range []struct {
+		verb  string
+		items []APIDiffRes
+	}{{"no longer implements", removed}, {"now implements", added}} {
+		for _, r := range x.items {
+			tname := strings.TrimSuffix(strings.TrimPrefix(r.Label, "Type `"), "` Implements")
+			lines = append(lines, "- "+links.withPosition(fmt.Sprintf("`%s`: `%s` %s `%s`", r.Path, tname, x.verb, r.X), r.Pos, false))
+		}
+	}
+	sort.Strings(lines)
+	sb.WriteString("\n### Interface Implementations\n\n")
+	sb.WriteString(strings.Join(lines, "\n") + "\n")
+}
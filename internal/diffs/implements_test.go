@@ -0,0 +1,93 @@
+package diffs
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestImplementedInterfaces(t *testing.T) {
+	pkg := typeCheck(t, `package p
+
+type Doer interface{ Do() error }
+
+type Empty interface{}
+
+type List[T any] interface{ Get() T }
+
+type Job struct{}
+
+func (*Job) Do() error             { return nil }
+func (Job) Close() error           { return nil }
+func (Job) String() string         { return "" }
+func (Job) MarshalJSON() ([]byte, error) { return nil, nil }
+
+type ByName []string
+
+func (ByName) Len() int           { return 0 }
+func (ByName) Less(i, j int) bool { return false }
+func (ByName) Swap(i, j int)      {}
+
+type Err string
+
+func (e Err) Error() string { return string(e) }
+`)
+	ifaces := moduleInterfaces([]*packages.Package{{PkgPath: "example.com/p", Types: pkg}})
+	require.Len(t, ifaces, 1, "empty and generic interfaces are skipped")
+
+	implements := func(name string) []string {
+		//nolint:errcheck
+		o := pkg.Scope().Lookup(name).(*types.TypeName)
+		apkg := APIPackage{TypeFacts: map[string]APITypeFacts{}}
+		atype := snapshotType(o, &apkg)
+		return implementedInterfaces(o, &atype, ifaces, DefaultInterfaces)
+	}
+
+	assert.Equal(t, []string{"encoding/json.Marshaler", "example.com/p.Doer", "fmt.Stringer", "io.Closer"}, implements("Job"))
+	assert.Equal(t, []string{"sort.Interface"}, implements("ByName"))
+	assert.Equal(t, []string{"error"}, implements("Err"))
+	assert.Empty(t, implements("Doer"))
+}
+
+func TestParseInterface(t *testing.T) {
+	iface, err := ParseInterface("io.Reader")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Read([]byte) -> (int, error)"}, iface.Methods)
+
+	iface, err = ParseInterface("example.com/x.Pinger=Ping() -> (error); Name() -> (string)")
+	require.NoError(t, err)
+	assert.Equal(t, WellKnownInterface{Name: "example.com/x.Pinger", Methods: []string{"Ping() -> (error)", "Name() -> (string)"}}, iface)
+
+	_, err = ParseInterface("io.Nope")
+	assert.Error(t, err)
+	_, err = ParseInterface("x=")
+	assert.Error(t, err)
+}
+
+func TestDiffAPI_Implements(t *testing.T) {
+	oldAPI := map[string]APIPackage{"p": {Types: map[string]APIType{
+		"File": {Kind: "struct", Methods: []string{"Close() -> (error)", "Read([]byte) -> (int, error)"}, Implements: []string{"io.Closer", "io.Reader"}},
+	}}}
+	newAPI := map[string]APIPackage{"p": {Types: map[string]APIType{
+		"File": {Kind: "struct", Methods: []string{"Read([]byte) -> (int, error)", "String() -> (string)"}, Implements: []string{"fmt.Stringer", "io.Reader"}},
+	}}}
+
+	d := DiffAPI(oldAPI, newAPI)
+	require.Len(t, d.ImplementsRemoved, 1)
+	require.Len(t, d.ImplementsAdded, 1)
+
+	var breaking []string
+	for _, c := range d.Incompatible() {
+		breaking = append(breaking, c.Kind+": "+c.Symbol)
+	}
+	assert.Contains(t, breaking, "Removed Type `File` Implements: io.Closer")
+
+	out := d.String()
+	assert.Contains(t, out, "- [Interface Implementations](#interface-implementations)\n")
+	assert.Contains(t, out, "\n### Interface Implementations\n\n"+
+		"- `p`: `File` no longer implements `io.Closer`\n"+
+		"- `p`: `File` now implements `fmt.Stringer`\n")
+}
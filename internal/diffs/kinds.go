@@ -0,0 +1,79 @@
+package diffs
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// typeKind names the kind of t's underlying type.
+func typeKind(t types.Type) string {
+	switch t.Underlying().(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	case *types.Basic:
+		return "basic"
+	case *types.Pointer:
+		return "pointer"
+	case *types.Slice:
+		return "slice"
+	case *types.Array:
+		return "array"
+	case *types.Map:
+		return "map"
+	case *types.Chan:
+		return "chan"
+	case *types.Signature:
+		return "func"
+	default:
+		return fmt.Sprintf("%T", t.Underlying())
+	}
+}
+
+// typeForm describes how a type is declared, e.g. "struct type" or "alias of pkg.T".
+func typeForm(t *APIType) string {
+	if t.Alias {
+		return "alias of " + t.Underlying
+	}
+	return t.Kind + " type"
+}
+
+// typeDecl renders a type declaration, e.g. "type ID int" or "type ID = int".
+func typeDecl(name string, t *APIType) string {
+	if t.Alias {
+		return fmt.Sprintf("type %s = %s", name, t.Underlying)
+	}
+	return fmt.Sprintf("type %s %s", name, t.Underlying)
+}
+
+// diffTypeForm reports a changed kind (struct -> interface, alias -> defined type, ...),
+// or, for the same kind, a changed underlying type (type ID int -> type ID string).
+// Struct and interface bodies are covered by the field and method diffs instead.
+func diffTypeForm(path, tname string, oldType, newType *APIType) (kindChanged, underlyingChanged []APIDiffChange) {
+	if oldType.Kind == "" || newType.Kind == "" {
+		return nil, nil
+	}
+	if oldType.Kind != newType.Kind || oldType.Alias != newType.Alias {
+		return []APIDiffChange{{
+			Label: "Type Kind",
+			Path:  path,
+			Name:  tname,
+			Old:   fmt.Sprintf("%s: %s", tname, typeForm(oldType)),
+			New:   fmt.Sprintf("%s: %s", tname, typeForm(newType)),
+		}}, nil
+	}
+	if oldType.Underlying == newType.Underlying {
+		return nil, nil
+	}
+	if !oldType.Alias && (oldType.Kind == "struct" || oldType.Kind == "interface") {
+		return nil, nil
+	}
+	return nil, []APIDiffChange{{
+		Label: "Underlying Type",
+		Path:  path,
+		Name:  tname,
+		Old:   typeDecl(tname, oldType),
+		New:   typeDecl(tname, newType),
+	}}
+}
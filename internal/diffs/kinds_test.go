@@ -0,0 +1,53 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAPI_TypeForms(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Types: map[string]APIType{
+				"ID":      {Kind: "basic", Underlying: "int"},
+				"Shape":   {Kind: "struct", Underlying: "struct{X int}", Fields: []string{"X int"}},
+				"Handle":  {Kind: "basic", Alias: true, Underlying: "int"},
+				"Options": {Kind: "struct", Underlying: "struct{a int}"},
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Types: map[string]APIType{
+				"ID":      {Kind: "basic", Underlying: "string"},
+				"Shape":   {Kind: "interface", Underlying: "interface{Area() float64}", Methods: []string{"Area() -> (float64)"}},
+				"Handle":  {Kind: "basic", Underlying: "int"},
+				"Options": {Kind: "struct", Underlying: "struct{b string}"},
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+
+	require.Len(t, apiDiff.TypesUnderlyingChanged, 1)
+	assert.Equal(t, "type ID int", apiDiff.TypesUnderlyingChanged[0].Old)
+	assert.Equal(t, "type ID string", apiDiff.TypesUnderlyingChanged[0].New)
+
+	require.Len(t, apiDiff.TypesKindChanged, 2)
+	kinds := make(map[string]APIDiffChange)
+	for _, c := range apiDiff.TypesKindChanged {
+		kinds[c.Name] = c
+	}
+	assert.Equal(t, "Shape: struct type", kinds["Shape"].Old)
+	assert.Equal(t, "Shape: interface type", kinds["Shape"].New)
+	assert.Equal(t, "Handle: alias of int", kinds["Handle"].Old)
+	assert.Equal(t, "Handle: basic type", kinds["Handle"].New)
+
+	for _, c := range apiDiff.Compat {
+		if c.Kind == "Changed Type Kind" || c.Kind == "Changed Underlying Type" {
+			assert.False(t, c.Compatible, c.Symbol)
+		}
+	}
+}
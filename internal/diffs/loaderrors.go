@@ -0,0 +1,115 @@
+package diffs
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// APIUnanalysed is a package that failed to load on at least one side, so its API was not compared.
+type APIUnanalysed struct {
+	Path      string   `json:"path"`
+	OldErrors []string `json:"old_errors,omitempty"`
+	NewErrors []string `json:"new_errors,omitempty"`
+}
+
+// loadFailed reports whether a package is in the snapshot only to record its load errors.
+func (p *APIPackage) loadFailed() bool {
+	return len(p.LoadErrors) > 0
+}
+
+// loadError renders a load error with its position relative to the module root,
+// since the snapshot is taken in a temporary worktree.
+func loadError(err packages.Error, module *packages.Module) string {
+	pos := err.Pos
+	if module != nil && module.Dir != "" {
+		pos = strings.TrimPrefix(pos, module.Dir+string(filepath.Separator))
+	}
+	if pos == "" || pos == "-" {
+		return err.Msg
+	}
+	return pos + ": " + err.Msg
+}
+
+// unanalysedPackages lists the packages that failed to load on either side. They are left out of
+// every other comparison, so a package that does not compile is never reported as removed or added.
+func unanalysedPackages(oldAPI, newAPI map[string]APIPackage) []APIUnanalysed {
+	byPath := make(map[string]*APIUnanalysed)
+	get := func(path string) *APIUnanalysed {
+		if u, ok := byPath[path]; ok {
+			return u
+		}
+		u := &APIUnanalysed{Path: path}
+		byPath[path] = u
+		return u
+	}
+	for path, pkg := range oldAPI {
+		if pkg.loadFailed() {
+			get(path).OldErrors = pkg.LoadErrors
+		}
+	}
+	for path, pkg := range newAPI {
+		if pkg.loadFailed() {
+			get(path).NewErrors = pkg.LoadErrors
+		}
+	}
+
+	res := make([]APIUnanalysed, 0, len(byPath))
+	for _, u := range byPath {
+		res = append(res, *u)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res
+}
+
+// withoutPackages returns api without the given packages.
+func withoutPackages(api map[string]APIPackage, skip []APIUnanalysed) map[string]APIPackage {
+	if len(skip) == 0 {
+		return api
+	}
+	res := make(map[string]APIPackage, len(api))
+	for path, pkg := range api {
+		res[path] = pkg
+	}
+	for _, u := range skip {
+		delete(res, u.Path)
+	}
+	return res
+}
+
+// FailedPackages lists the packages of the snapshots that could not be loaded, with the first error of each.
+func FailedPackages(apis ...map[string]APIPackage) []string {
+	var res []string
+	for _, api := range apis {
+		for path, pkg := range api {
+			if pkg.loadFailed() {
+				res = append(res, fmt.Sprintf("%s: %s", path, pkg.LoadErrors[0]))
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// writeUnanalysedSection lists the packages that failed to load, with the side and the errors.
+func writeUnanalysedSection(sb *strings.Builder, unanalysed []APIUnanalysed) {
+	if len(unanalysed) == 0 {
+		return
+	}
+	sb.WriteString("\n### Packages that could not be analysed\n\n")
+	sb.WriteString("_These packages failed to load, so their API was not compared. They are not counted as added or removed._\n\n")
+	for _, u := range unanalysed {
+		sb.WriteString(fmt.Sprintf("- `%s`\n", u.Path))
+		for _, x := range []struct {
+			ref    string
+			errors []string
+		}{{"old", u.OldErrors}, {"new", u.NewErrors}} {
+			for _, e := range x.errors {
+				sb.WriteString(fmt.Sprintf("    - %s: `%s`\n", x.ref, e))
+			}
+		}
+	}
+}
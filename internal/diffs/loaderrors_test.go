@@ -0,0 +1,52 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestDiffAPI_LoadErrors(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"example.com/m/api":    {Funcs: []string{"Get() -> (error)"}, Types: map[string]APIType{}},
+		"example.com/m/broken": {LoadErrors: []string{"broken.go:3:1: expected declaration"}},
+		"example.com/m/store":  {Funcs: []string{"Open()"}, Types: map[string]APIType{}},
+	}
+	newAPI := map[string]APIPackage{
+		"example.com/m/api":   {LoadErrors: []string{"api.go:5:9: undefined: x"}},
+		"example.com/m/store": {Funcs: []string{"Open()"}, Types: map[string]APIType{}},
+		"example.com/m/new":   {LoadErrors: []string{"new.go:1:1: expected 'package'"}},
+	}
+
+	d := DiffAPI(oldAPI, newAPI)
+	assert.Empty(t, d.PackagesRemoved)
+	assert.Empty(t, d.PackagesAdded)
+	assert.Empty(t, d.FuncsRemoved)
+	assert.Empty(t, d.Incompatible())
+	require.Equal(t, []APIUnanalysed{
+		{Path: "example.com/m/api", NewErrors: []string{"api.go:5:9: undefined: x"}},
+		{Path: "example.com/m/broken", OldErrors: []string{"broken.go:3:1: expected declaration"}},
+		{Path: "example.com/m/new", NewErrors: []string{"new.go:1:1: expected 'package'"}},
+	}, d.Unanalysed)
+
+	out := d.String()
+	assert.Contains(t, out, "- [Packages that could not be analysed](#packages-that-could-not-be-analysed)\n")
+	assert.Contains(t, out, "- `example.com/m/api`\n    - new: `api.go:5:9: undefined: x`\n")
+	assert.Contains(t, out, "- `example.com/m/broken`\n    - old: `broken.go:3:1: expected declaration`\n")
+}
+
+func TestFailedPackages(t *testing.T) {
+	assert.Equal(t, []string{"a: x.go:1:1: boom", "b: first"}, FailedPackages(
+		map[string]APIPackage{"b": {LoadErrors: []string{"first", "second"}}, "ok": {}},
+		map[string]APIPackage{"a": {LoadErrors: []string{"x.go:1:1: boom"}}},
+	))
+	assert.Empty(t, FailedPackages(map[string]APIPackage{"ok": {}}))
+}
+
+func TestLoadError(t *testing.T) {
+	module := &packages.Module{Path: "example.com/m", Dir: "/tmp/wt"}
+	assert.Equal(t, "api/a.go:3:1: undefined: x", loadError(packages.Error{Pos: "/tmp/wt/api/a.go:3:1", Msg: "undefined: x"}, module))
+	assert.Equal(t, "no Go files", loadError(packages.Error{Pos: "-", Msg: "no Go files"}, module))
+}
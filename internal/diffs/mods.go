@@ -15,11 +15,25 @@ type GoModDiff struct {
 	DependenciesAdded   []string
 	DependenciesRemoved []string
 	DependenciesUpdated []string
+
+	// Exposed lists changed dependencies whose types appear in exported API, see DiffExposedDependencies.
+	Exposed []DependencyExposure
 }
 
 func (d *GoModDiff) String() string {
 	var b strings.Builder
 	b.WriteString("\n---\n## go.mod Changes\n\n")
+
+	if len(d.Exposed) > 0 {
+		b.WriteString("### Exposed dependencies changed\n")
+		for _, e := range d.Exposed {
+			b.WriteString("- " + e.String() + "\n")
+			for _, sym := range e.Symbols {
+				b.WriteString("  - `" + sym + "`\n")
+			}
+		}
+		b.WriteString("\n")
+	}
 	b.WriteString("<details>\n<summary>Click to expand</summary>\n\n")
 
 	if len(d.DependenciesAdded) > 0 {
@@ -147,3 +161,12 @@ func parseGoMod(path string) *modfile.File {
 
 	return f
 }
+
+// ModulePath returns the module path declared in the given go.mod file, or "" if unavailable.
+func ModulePath(goModPath string) string {
+	f := parseGoMod(goModPath)
+	if f.Module == nil {
+		return ""
+	}
+	return f.Module.Mod.Path
+}
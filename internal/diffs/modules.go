@@ -0,0 +1,181 @@
+package diffs
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashmap-kz/relimpact/internal/loggr"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Module is a Go module of a repository.
+type Module struct {
+	Dir  string `json:"dir"`  // relative to the repository root, "." for the root module
+	Path string `json:"path"` // module path declared in go.mod
+}
+
+// DiscoverModules finds the modules of the repository checked out at repoDir:
+// the ones listed in go.work if there is one, otherwise every go.mod in the tree.
+// vendor and testdata directories, and directories starting with "." or "_", are skipped.
+func DiscoverModules(repoDir string) []Module {
+	var dirs []string
+	if data, err := os.ReadFile(filepath.Join(repoDir, "go.work")); err == nil {
+		work, err := modfile.ParseWork("go.work", data, nil)
+		if err != nil {
+			loggr.Warnf("could not parse go.work: %v", err)
+		} else {
+			for _, u := range work.Use {
+				dirs = append(dirs, filepath.Clean(u.Path))
+			}
+		}
+	}
+	if dirs == nil {
+		dirs = findGoModDirs(repoDir)
+	}
+
+	var modules []Module
+	for _, dir := range dirs {
+		path := ModulePath(filepath.Join(repoDir, dir, "go.mod"))
+		if path == "" {
+			continue
+		}
+		modules = append(modules, Module{Dir: filepath.ToSlash(dir), Path: path})
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Dir < modules[j].Dir })
+	return modules
+}
+
+func findGoModDirs(repoDir string) []string {
+	var dirs []string
+	//nolint:errcheck
+	_ = filepath.WalkDir(repoDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != repoDir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			if rel, err := filepath.Rel(repoDir, filepath.Dir(path)); err == nil {
+				dirs = append(dirs, rel)
+			}
+		}
+		return nil
+	})
+	return dirs
+}
+
+// IsMultiModule reports whether a repository needs to be reported module by module,
+// i.e. it is anything other than a single module at the repository root.
+func IsMultiModule(oldMods, newMods []Module) bool {
+	single := func(mods []Module) bool {
+		return len(mods) == 0 || len(mods) == 1 && mods[0].Dir == "."
+	}
+	return !single(oldMods) || !single(newMods)
+}
+
+// ModulesDiff pairs the modules of two refs by module path.
+type ModulesDiff struct {
+	Added   []Module
+	Removed []Module
+	Kept    [][2]Module // old and new, which may live in different directories
+}
+
+func DiffModules(oldMods, newMods []Module) *ModulesDiff {
+	d := &ModulesDiff{}
+	oldByPath := make(map[string]Module)
+	for _, m := range oldMods {
+		oldByPath[m.Path] = m
+	}
+	newByPath := make(map[string]Module)
+	for _, m := range newMods {
+		newByPath[m.Path] = m
+		if o, ok := oldByPath[m.Path]; ok {
+			d.Kept = append(d.Kept, [2]Module{o, m})
+		} else {
+			d.Added = append(d.Added, m)
+		}
+	}
+	for _, m := range oldMods {
+		if _, ok := newByPath[m.Path]; !ok {
+			d.Removed = append(d.Removed, m)
+		}
+	}
+	return d
+}
+
+func (d *ModulesDiff) String() string {
+	var sb strings.Builder
+	sb.WriteString("## Modules\n\n")
+	sb.WriteString("| Modules | Added | Removed | Total |\n")
+	sb.WriteString("|---------|------:|--------:|------:|\n")
+	sb.WriteString(fmt.Sprintf("| %-7s | %5d | %7d | %5d |\n", "Count", len(d.Added), len(d.Removed), len(d.Kept)+len(d.Added)))
+
+	write := func(label string, mods []Module) {
+		if len(mods) == 0 {
+			return
+		}
+		sb.WriteString(fmt.Sprintf("\n### Modules %s\n\n", label))
+		for _, m := range mods {
+			sb.WriteString(fmt.Sprintf("- `%s` (`%s`)\n", m.Path, m.Dir))
+		}
+	}
+	write("Added", d.Added)
+	write("Removed", d.Removed)
+	return sb.String()
+}
+
+// OwnerModule returns the directory of the innermost module containing the repository-relative file path,
+// or "" when the file is outside every module.
+func OwnerModule(path string, modules []Module) string {
+	owner, depth := "", -1
+	for _, m := range modules {
+		if m.Dir != "." && path != m.Dir && !strings.HasPrefix(path, m.Dir+"/") {
+			continue
+		}
+		d := strings.Count(m.Dir, "/")
+		if m.Dir == "." {
+			d = -1
+		}
+		if owner == "" || d > depth {
+			owner, depth = m.Dir, d
+		}
+	}
+	return owner
+}
+
+// Filter returns the file changes whose repository-relative path satisfies keep.
+func (s *OtherFilesDiffSummary) Filter(keep func(path string) bool) *OtherFilesDiffSummary {
+	filter := func(files []string) []string {
+		var res []string
+		for _, f := range files {
+			if keep(f) {
+				res = append(res, f)
+			}
+		}
+		return res
+	}
+	res := &OtherFilesDiffSummary{}
+	for _, d := range s.Diffs {
+		fd := OtherFileDiff{
+			Ext:      d.Ext,
+			Added:    filter(d.Added),
+			Modified: filter(d.Modified),
+			Removed:  filter(d.Removed),
+			Other:    filter(d.Other),
+		}
+		if len(fd.Added)+len(fd.Modified)+len(fd.Removed)+len(fd.Other) > 0 {
+			res.Diffs = append(res.Diffs, fd)
+		}
+	}
+	return res
+}
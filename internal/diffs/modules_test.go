@@ -0,0 +1,82 @@
+package diffs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeModule(t *testing.T, dir, path string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	writeGoMod(t, dir, "module "+path+"\n\ngo 1.22\n")
+}
+
+func TestDiscoverModules(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, root, "example.com/m")
+	writeModule(t, filepath.Join(root, "tools"), "example.com/m/tools")
+	writeModule(t, filepath.Join(root, "services", "api"), "example.com/api")
+	writeModule(t, filepath.Join(root, "vendor", "x"), "example.com/x")
+	writeModule(t, filepath.Join(root, "testdata", "y"), "example.com/y")
+	writeModule(t, filepath.Join(root, "_old"), "example.com/old")
+
+	assert.Equal(t, []Module{
+		{Dir: ".", Path: "example.com/m"},
+		{Dir: "services/api", Path: "example.com/api"},
+		{Dir: "tools", Path: "example.com/m/tools"},
+	}, DiscoverModules(root))
+}
+
+func TestDiscoverModules_GoWork(t *testing.T) {
+	root := t.TempDir()
+	writeModule(t, filepath.Join(root, "a"), "example.com/a")
+	writeModule(t, filepath.Join(root, "b"), "example.com/b")
+	writeModule(t, filepath.Join(root, "c"), "example.com/c") // not in the workspace
+	work := "go 1.22\n\nuse (\n\t./a\n\t./b\n)\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.work"), []byte(work), 0o600))
+
+	assert.Equal(t, []Module{
+		{Dir: "a", Path: "example.com/a"},
+		{Dir: "b", Path: "example.com/b"},
+	}, DiscoverModules(root))
+}
+
+func TestDiffModules(t *testing.T) {
+	oldMods := []Module{{Dir: ".", Path: "example.com/m"}, {Dir: "tools", Path: "example.com/m/tools"}}
+	newMods := []Module{{Dir: ".", Path: "example.com/m"}, {Dir: "api", Path: "example.com/api"}}
+
+	d := DiffModules(oldMods, newMods)
+	assert.Equal(t, []Module{{Dir: "api", Path: "example.com/api"}}, d.Added)
+	assert.Equal(t, []Module{{Dir: "tools", Path: "example.com/m/tools"}}, d.Removed)
+	assert.Len(t, d.Kept, 1)
+
+	out := d.String()
+	assert.Contains(t, out, "| Count   |     1 |       1 |     2 |")
+	assert.Contains(t, out, "### Modules Added\n\n- `example.com/api` (`api`)")
+	assert.Contains(t, out, "### Modules Removed\n\n- `example.com/m/tools` (`tools`)")
+
+	assert.False(t, IsMultiModule(oldMods[:1], newMods[:1]))
+	assert.True(t, IsMultiModule(oldMods[:1], newMods))
+}
+
+func TestOwnerModule(t *testing.T) {
+	mods := []Module{{Dir: "."}, {Dir: "tools"}, {Dir: "tools/gen"}}
+	assert.Equal(t, ".", OwnerModule("config.yaml", mods))
+	assert.Equal(t, "tools", OwnerModule("tools/x.sql", mods))
+	assert.Equal(t, "tools/gen", OwnerModule("tools/gen/a.json", mods))
+	assert.Equal(t, ".", OwnerModule("toolsx/a.json", mods))
+	assert.Equal(t, "", OwnerModule("a.json", mods[1:]))
+}
+
+func TestOtherFilesDiffSummary_Filter(t *testing.T) {
+	s := &OtherFilesDiffSummary{Diffs: []OtherFileDiff{
+		{Ext: ".sql", Added: []string{"a/1.sql", "b/2.sql"}},
+		{Ext: ".yaml", Modified: []string{"b/c.yaml"}},
+	}}
+	got := s.Filter(func(path string) bool { return OwnerModule(path, []Module{{Dir: "a"}}) == "a" })
+	assert.Equal(t, []OtherFileDiff{{Ext: ".sql", Added: []string{"a/1.sql"}}}, got.Diffs)
+}
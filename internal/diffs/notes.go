@@ -0,0 +1,132 @@
+package diffs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashmap-kz/relimpact/internal/loggr"
+)
+
+// NotesRef is the git notes ref snapshots are stored under, one note per commit. A note holds
+// the snapshots of every variant (module, platforms, docs, interfaces) taken at that commit.
+const NotesRef = "refs/notes/relimpact"
+
+// notesDoc is the content of a note: cache entries by variant, "" for the default one.
+type notesDoc map[string]cacheEntry
+
+// notesMu serializes note writes of this process: git fails to update a notes ref locked by another writer.
+var notesMu sync.Mutex
+
+// readNotes returns the note of ref attached to sha, or an empty doc.
+func readNotes(dir, ref, sha string) notesDoc {
+	cmd := exec.Command("git", "notes", "--ref", ref, "show", sha)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return notesDoc{} // no note yet
+	}
+	doc := notesDoc{}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		loggr.Debugf("ignoring unreadable note. sha=%s: %v", sha, err)
+		return notesDoc{}
+	}
+	return doc
+}
+
+// readNote returns the snapshot of a variant stored in the note of sha when it can be used for want.
+func readNote(dir, sha, variant string, want *cacheHeader) (map[string]APIPackage, bool) {
+	entry, ok := readNotes(dir, NotesRef, sha)[variant]
+	if !ok {
+		return nil, false
+	}
+	api, reason := decodeCache(&entry, want)
+	if reason != "" {
+		loggr.Debugf("note not used: %s. sha=%s, variant=%q", reason, sha, variant)
+		return nil, false
+	}
+	return api, true
+}
+
+// writeNote stores the snapshot of a variant in the note of sha, keeping the other variants.
+func writeNote(dir, sha, variant string, header cacheHeader, api map[string]APIPackage) error {
+	entry, err := newCacheEntry(header, api)
+	if err != nil {
+		return err
+	}
+	return updateNote(dir, sha, func(doc notesDoc) bool {
+		doc[variant] = entry
+		return true
+	})
+}
+
+// updateNote rewrites the note of sha with update, unless it reports no change.
+// A concurrent relimpact process updating the notes ref makes git fail; the write is retried.
+func updateNote(dir, sha string, update func(notesDoc) bool) error {
+	notesMu.Lock()
+	defer notesMu.Unlock()
+	wait := 50 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		doc := readNotes(dir, NotesRef, sha)
+		if !update(doc) {
+			return nil
+		}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		cmd := exec.Command("git", "notes", "--ref", NotesRef, "add", "-f", "-F", "-", sha)
+		cmd.Dir = dir
+		cmd.Stdin = bytes.NewReader(data)
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		if attempt == 5 {
+			return fmt.Errorf("git notes add: %w: %s", err, bytes.TrimSpace(out))
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// MergeNotes adds the variants stored in the notes ref from (e.g. fetched from a remote) to the notes
+// of NotesRef attached to the same commits. Variants present on both sides keep the local snapshot.
+// It returns the number of notes updated.
+func MergeNotes(dir, from string) (int, error) {
+	cmd := exec.Command("git", "notes", "--ref", from, "list")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("git notes list %s: %w", from, err)
+	}
+	updated := 0
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line) // note blob, annotated commit
+		if len(fields) != 2 {
+			continue
+		}
+		theirs := readNotes(dir, from, fields[1])
+		changed := false
+		err := updateNote(dir, fields[1], func(doc notesDoc) bool {
+			for variant, entry := range theirs {
+				if _, ok := doc[variant]; !ok {
+					doc[variant] = entry
+					changed = true
+				}
+			}
+			return changed
+		})
+		if err != nil {
+			return updated, err
+		}
+		if changed {
+			updated++
+		}
+	}
+	return updated, nil
+}
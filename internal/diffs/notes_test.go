@@ -0,0 +1,104 @@
+package diffs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashmap-kz/relimpact/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotes_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	testutils.RunGit(t, dir, "init")
+	testutils.RunGit(t, dir, "config", "user.name", "Test User")
+	testutils.RunGit(t, dir, "config", "user.email", "test@example.com")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n"), 0o600))
+	testutils.RunGit(t, dir, "add", "go.mod")
+	testutils.RunGit(t, dir, "commit", "-m", "initial commit")
+	sha := getGitCommitSHA(dir)
+
+	header := testHeader()
+	_, ok := readNote(dir, sha, "", &header)
+	assert.False(t, ok)
+
+	api := map[string]APIPackage{"example.com/m": {Funcs: []string{"F()"}}}
+	docs := map[string]APIPackage{"example.com/m": {Funcs: []string{"F()"}, Docs: map[string]string{"F": "F does it."}}}
+	require.NoError(t, writeNote(dir, sha, "", header, api))
+	require.NoError(t, writeNote(dir, sha, "docs", header, docs))
+
+	got, ok := readNote(dir, sha, "", &header)
+	require.True(t, ok, "other variants are kept")
+	assert.Equal(t, api, got)
+	got, ok = readNote(dir, sha, "docs", &header)
+	require.True(t, ok)
+	assert.Equal(t, docs, got)
+
+	other := testHeader()
+	other.GoVersion = "go1.99.0"
+	_, ok = readNote(dir, sha, "", &other)
+	assert.False(t, ok, "notes written for another build context are not used")
+}
+
+func TestMergeNotes(t *testing.T) {
+	dir := t.TempDir()
+	testutils.RunGit(t, dir, "init")
+	testutils.RunGit(t, dir, "config", "user.name", "Test User")
+	testutils.RunGit(t, dir, "config", "user.email", "test@example.com")
+	testutils.RunGit(t, dir, "commit", "--allow-empty", "-m", "initial commit")
+	sha := getGitCommitSHA(dir)
+
+	header := testHeader()
+	ours := map[string]APIPackage{"example.com/m": {Funcs: []string{"F()"}}}
+	require.NoError(t, writeNote(dir, sha, "", header, ours))
+
+	// a note fetched from a remote, with the same variant and another one
+	theirs, err := newCacheEntry(header, map[string]APIPackage{"example.com/m": {Funcs: []string{"G()"}}})
+	require.NoError(t, err)
+	data, err := json.Marshal(notesDoc{"": theirs, "docs": theirs})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "note.json"), data, 0o600))
+	testutils.RunGit(t, dir, "notes", "--ref", "refs/notes/remotes/origin/relimpact", "add", "-F", "note.json", sha)
+
+	updated, err := MergeNotes(dir, "refs/notes/remotes/origin/relimpact")
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+
+	got, ok := readNote(dir, sha, "", &header)
+	require.True(t, ok)
+	assert.Equal(t, ours, got, "the local snapshot of a variant is kept")
+	_, ok = readNote(dir, sha, "docs", &header)
+	assert.True(t, ok)
+
+	updated, err = MergeNotes(dir, "refs/notes/remotes/origin/relimpact")
+	require.NoError(t, err)
+	assert.Zero(t, updated)
+}
+
+func TestSnapshotAPIWith_NotesOnCacheHit(t *testing.T) {
+	t.Setenv("RELIMPACT_API_CACHE_DIR", t.TempDir())
+	dir := t.TempDir()
+	testutils.RunGit(t, dir, "init")
+	testutils.RunGit(t, dir, "config", "user.name", "Test User")
+	testutils.RunGit(t, dir, "config", "user.email", "test@example.com")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.22\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "m.go"), []byte("package m\n\nfunc F() {}\n"), 0o600))
+	testutils.RunGit(t, dir, "add", "-A")
+	testutils.RunGit(t, dir, "commit", "-m", "init")
+	sha := getGitCommitSHA(dir)
+
+	// the first run fills the local cache only
+	api := SnapshotAPIWith(dir, SnapshotOptions{})
+	require.Contains(t, api, "example.com/m")
+	assert.Empty(t, readNotes(dir, NotesRef, sha))
+
+	opts := SnapshotOptions{Notes: true}
+	assert.Equal(t, []string{"F()"}, SnapshotAPIWith(dir, opts)["example.com/m"].Funcs)
+	header := newCacheHeader(dir, &opts)
+	got, ok := readNote(dir, sha, "", &header)
+	require.True(t, ok, "the note is written on a local cache hit")
+	assert.Equal(t, []string{"F()"}, got["example.com/m"].Funcs)
+}
@@ -58,6 +58,8 @@ func (s *OtherFilesDiffSummary) String() string {
 	return b.String()
 }
 
+// DiffOther groups the files changed between oldRef and newRef by extension. An empty newRef compares
+// oldRef with the working tree of workDir: staged, unstaged and untracked changes.
 func DiffOther(workDir, oldRef, newRef string, includeExts []string) *OtherFilesDiffSummary {
 	changes := collectOtherFileChanges(workDir, oldRef, newRef, includeExts)
 
@@ -93,10 +95,33 @@ func DiffOther(workDir, oldRef, newRef string, includeExts []string) *OtherFiles
 	return &summary
 }
 
+// untrackedFiles lists the files of the working tree git does not track nor ignore, as added
+// in 'git diff --name-status' format: 'git diff' against the working tree leaves them out.
+func untrackedFiles(workDir string) []string {
+	cmd := exec.Command("git", "ls-files", "--others", "--exclude-standard", "--full-name")
+	cmd.Dir = workDir
+	out, err := cmd.Output()
+	if err != nil {
+		loggr.Errorf("git ls-files failed: %v", err)
+		return nil
+	}
+	var lines []string
+	for _, file := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if file != "" {
+			lines = append(lines, "A\t"+file)
+		}
+	}
+	return lines
+}
+
 func collectOtherFileChanges(workDir, oldRef, newRef string, includeExts []string) map[string]map[string][]string {
 	changes := make(map[string]map[string][]string)
 
-	cmd := exec.Command("git", "diff", "--name-status", oldRef, newRef)
+	args := []string{"diff", "--name-status", oldRef}
+	if newRef != "" {
+		args = append(args, newRef)
+	}
+	cmd := exec.Command("git", args...)
 	cmd.Dir = workDir
 	out, err := cmd.Output()
 	if err != nil {
@@ -105,6 +130,9 @@ func collectOtherFileChanges(workDir, oldRef, newRef string, includeExts []strin
 	}
 
 	lines := strings.Split(string(out), "\n")
+	if newRef == "" {
+		lines = append(lines, untrackedFiles(workDir)...)
+	}
 	includeSet := make(map[string]bool)
 	for _, ext := range includeExts {
 		includeSet[ext] = true
@@ -0,0 +1,256 @@
+package diffs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Platform is a build configuration to snapshot the API under.
+type Platform struct {
+	GOOS   string   `json:"goos"`
+	GOARCH string   `json:"goarch"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// String renders a platform as "GOOS/GOARCH" or "GOOS/GOARCH:tag1,tag2".
+func (p Platform) String() string {
+	s := p.GOOS + "/" + p.GOARCH
+	if len(p.Tags) > 0 {
+		s += ":" + strings.Join(p.Tags, ",")
+	}
+	return s
+}
+
+// Env returns the environment that selects the platform for the go command.
+func (p Platform) Env() []string {
+	return []string{"GOOS=" + p.GOOS, "GOARCH=" + p.GOARCH, "CGO_ENABLED=0"}
+}
+
+// BuildFlags returns the build flags that select the platform's tags.
+func (p Platform) BuildFlags() []string {
+	if len(p.Tags) == 0 {
+		return nil
+	}
+	return []string{"-tags=" + strings.Join(p.Tags, ",")}
+}
+
+// ParsePlatform parses "GOOS/GOARCH" with optional build tags, e.g. "linux/amd64:integration,e2e".
+func ParsePlatform(s string) (Platform, error) {
+	target, tags, _ := strings.Cut(s, ":")
+	goos, goarch, ok := strings.Cut(target, "/")
+	if !ok || goos == "" || goarch == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected GOOS/GOARCH[:tags]", s)
+	}
+	p := Platform{GOOS: goos, GOARCH: goarch}
+	if tags != "" {
+		p.Tags = strings.Split(tags, ",")
+	}
+	return p, nil
+}
+
+// platformsKey identifies a platform matrix in cache file names.
+func platformsKey(platforms []Platform) string {
+	names := make([]string, 0, len(platforms))
+	for _, p := range platforms {
+		names = append(names, p.String())
+	}
+	return shortHash(strings.Join(names, ";"))
+}
+
+// shortHash is a short, file name friendly digest of s.
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// symbolKeys lists the symbols of a package that are tracked per platform:
+// funcs, vars, consts and types by name, fields and methods as "Type.Name".
+func symbolKeys(pkg *APIPackage) []string {
+	var keys []string
+	for _, list := range [][]string{pkg.Funcs, pkg.Vars, pkg.Consts} {
+		for _, x := range list {
+			keys = append(keys, symbolName(x))
+		}
+	}
+	for tname, t := range pkg.Types {
+		keys = append(keys, tname)
+		for _, list := range [][]string{t.Fields, t.Methods} {
+			for _, x := range list {
+				keys = append(keys, tname+"."+symbolName(x))
+			}
+		}
+	}
+	return keys
+}
+
+// symbolPlatforms returns the platforms where a symbol exists.
+func (p *APIPackage) symbolPlatforms(key string) []string {
+	if ps, ok := p.SymbolPlatforms[key]; ok {
+		return ps
+	}
+	return p.Platforms
+}
+
+// mergePlatforms merges per-platform snapshots into one. Every symbol that exists on any
+// platform is kept, with the declaration of the first platform it was seen on;
+// symbols missing on some platforms record where they do exist.
+func mergePlatforms(snaps []map[string]APIPackage, platforms []Platform) map[string]APIPackage {
+	merged := make(map[string]APIPackage)
+	seen := make(map[string]map[string][]string) // package -> symbol -> platforms
+
+	for i, snap := range snaps {
+		platform := platforms[i].String()
+		for path, pkg := range snap {
+			m, ok := merged[path]
+			if !ok {
+				m = pkg
+				m.Platforms = nil
+				seen[path] = make(map[string][]string)
+			} else {
+				mergePackage(&m, &pkg)
+			}
+			m.Platforms = append(m.Platforms, platform)
+			for _, key := range symbolKeys(&pkg) {
+				seen[path][key] = append(seen[path][key], platform)
+			}
+			merged[path] = m
+		}
+	}
+
+	for path, m := range merged {
+		for key, ps := range seen[path] {
+			if len(ps) == len(m.Platforms) {
+				continue
+			}
+			if m.SymbolPlatforms == nil {
+				m.SymbolPlatforms = make(map[string][]string)
+			}
+			m.SymbolPlatforms[key] = ps
+		}
+		merged[path] = m
+	}
+	return merged
+}
+
+// mergePackage adds the symbols of src that dst does not have yet.
+func mergePackage(dst, src *APIPackage) {
+	dst.Funcs = mergeNamed(dst.Funcs, src.Funcs)
+	dst.Vars = mergeNamed(dst.Vars, src.Vars)
+	dst.Consts = mergeNamed(dst.Consts, src.Consts)
+	dst.TypeFacts = mergeMap(dst.TypeFacts, src.TypeFacts)
+	dst.Signatures = mergeMap(dst.Signatures, src.Signatures)
+	dst.ValueTypes = mergeMap(dst.ValueTypes, src.ValueTypes)
+	dst.TypeParams = mergeMap(dst.TypeParams, src.TypeParams)
+	dst.ConstValues = mergeMap(dst.ConstValues, src.ConstValues)
+	dst.IotaBlocks = mergeMap(dst.IotaBlocks, src.IotaBlocks)
+	dst.Canonical = mergeMap(dst.Canonical, src.Canonical)
+	dst.Decls = mergeMap(dst.Decls, src.Decls)
+	dst.Deprecated = mergeMap(dst.Deprecated, src.Deprecated)
+	dst.Docs = mergeMap(dst.Docs, src.Docs)
+	dst.Exposes = mergeMap(dst.Exposes, src.Exposes)
+	dst.Positions = mergeMap(dst.Positions, src.Positions)
+	dst.LoadErrors = append(dst.LoadErrors, src.LoadErrors...)
+
+	if dst.Types == nil {
+		dst.Types = make(map[string]APIType)
+	}
+	for tname, st := range src.Types {
+		dt, ok := dst.Types[tname]
+		if !ok {
+			dst.Types[tname] = st
+			continue
+		}
+		dt.Fields = mergeNamed(dt.Fields, st.Fields)
+		dt.Methods = mergeNamed(dt.Methods, st.Methods)
+		dt.Tags = mergeMap(dt.Tags, st.Tags)
+		dt.Receivers = mergeMap(dt.Receivers, st.Receivers)
+		dt.Decls = mergeMap(dt.Decls, st.Decls)
+		dt.Implements = mergeNamed(dt.Implements, st.Implements)
+		dst.Types[tname] = dt
+	}
+}
+
+func mergeNamed(dst, src []string) []string {
+	for _, x := range src {
+		if !containsName(dst, symbolName(x)) {
+			dst = append(dst, x)
+		}
+	}
+	return dst
+}
+
+func mergeMap[V any](dst, src map[string]V) map[string]V {
+	for k, v := range src {
+		if dst == nil {
+			dst = make(map[string]V)
+		}
+		if _, ok := dst[k]; !ok {
+			dst[k] = v
+		}
+	}
+	return dst
+}
+
+// snapshotMatrix returns every platform a snapshot was taken on.
+func snapshotMatrix(api map[string]APIPackage) map[string]bool {
+	matrix := make(map[string]bool)
+	for _, pkg := range api {
+		for _, p := range pkg.Platforms {
+			matrix[p] = true
+		}
+	}
+	return matrix
+}
+
+// diffPlatforms reports symbols that still exist, but were removed from or added to some platforms.
+// Only platforms snapshotted on both sides are compared, so changing the matrix itself is not reported.
+func diffPlatforms(path string, oldPkg, newPkg *APIPackage, oldMatrix, newMatrix map[string]bool) (added, removed []APIDiffRes) {
+	if len(oldPkg.Platforms) == 0 || len(newPkg.Platforms) == 0 {
+		return nil, nil
+	}
+
+	oldKeys := make(map[string]bool)
+	for _, key := range symbolKeys(oldPkg) {
+		oldKeys[key] = true
+	}
+	keys := append([]string{""}, symbolKeys(newPkg)...) // "" is the package itself
+	for _, key := range keys {
+		if key != "" && !oldKeys[key] {
+			continue
+		}
+		oldPs, newPs := oldPkg.Platforms, newPkg.Platforms
+		if key != "" {
+			oldPs, newPs = oldPkg.symbolPlatforms(key), newPkg.symbolPlatforms(key)
+		}
+		name := key
+		if name == "" {
+			name = "package " + path
+		}
+		if lost := platformsMissing(oldPs, newPs, newMatrix); len(lost) > 0 {
+			removed = append(removed, APIDiffRes{Label: "Platforms", Path: path, X: name + " on " + strings.Join(lost, ", "), Pos: oldPkg.Positions[key]})
+		}
+		if gained := platformsMissing(newPs, oldPs, oldMatrix); len(gained) > 0 {
+			added = append(added, APIDiffRes{Label: "Platforms", Path: path, X: name + " on " + strings.Join(gained, ", "), Pos: newPkg.Positions[key]})
+		}
+	}
+	return added, removed
+}
+
+// platformsMissing returns the platforms of from that are in matrix but not in to.
+func platformsMissing(from, to []string, matrix map[string]bool) []string {
+	have := make(map[string]bool, len(to))
+	for _, p := range to {
+		have[p] = true
+	}
+	var res []string
+	for _, p := range from {
+		if matrix[p] && !have[p] {
+			res = append(res, p)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,90 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePlatform(t *testing.T) {
+	p, err := ParsePlatform("linux/amd64")
+	require.NoError(t, err)
+	assert.Equal(t, Platform{GOOS: "linux", GOARCH: "amd64"}, p)
+	assert.Nil(t, p.BuildFlags())
+
+	p, err = ParsePlatform("linux/arm64:integration,e2e")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"integration", "e2e"}, p.Tags)
+	assert.Equal(t, "linux/arm64:integration,e2e", p.String())
+	assert.Equal(t, []string{"-tags=integration,e2e"}, p.BuildFlags())
+
+	_, err = ParsePlatform("linux")
+	require.Error(t, err)
+}
+
+func TestMergePlatforms(t *testing.T) {
+	linux := map[string]APIPackage{
+		"pkg/fs": {
+			Funcs: []string{"Open(string) -> (error)", "Mmap(int) -> ([]byte)"},
+			Types: map[string]APIType{"File": {Kind: "struct", Fields: []string{"Fd int"}}},
+		},
+		"pkg/epoll": {Funcs: []string{"Wait()"}},
+	}
+	windows := map[string]APIPackage{
+		"pkg/fs": {
+			Funcs: []string{"Open(string) -> (error)"},
+			Types: map[string]APIType{"File": {Kind: "struct", Fields: []string{"Fd int", "Handle uintptr"}}},
+		},
+	}
+
+	merged := mergePlatforms(
+		[]map[string]APIPackage{linux, windows},
+		[]Platform{{GOOS: "linux", GOARCH: "amd64"}, {GOOS: "windows", GOARCH: "amd64"}},
+	)
+
+	fs := merged["pkg/fs"]
+	assert.Equal(t, []string{"linux/amd64", "windows/amd64"}, fs.Platforms)
+	assert.ElementsMatch(t, []string{"Open(string) -> (error)", "Mmap(int) -> ([]byte)"}, fs.Funcs)
+	assert.ElementsMatch(t, []string{"Fd int", "Handle uintptr"}, fs.Types["File"].Fields)
+	assert.Equal(t, map[string][]string{
+		"Mmap":        {"linux/amd64"},
+		"File.Handle": {"windows/amd64"},
+	}, fs.SymbolPlatforms)
+	assert.Equal(t, []string{"linux/amd64", "windows/amd64"}, fs.symbolPlatforms("Open"))
+
+	assert.Equal(t, []string{"linux/amd64"}, merged["pkg/epoll"].Platforms)
+}
+
+func TestDiffAPI_Platforms(t *testing.T) {
+	both := []string{"linux/amd64", "windows/amd64"}
+	oldAPI := map[string]APIPackage{
+		"pkg/fs": {
+			Funcs:     []string{"Open(string) -> (error)", "Sync()"},
+			Platforms: both,
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/fs": {
+			Funcs:           []string{"Open(string) -> (error)", "Sync()"},
+			Platforms:       both,
+			SymbolPlatforms: map[string][]string{"Sync": {"linux/amd64"}},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	assert.Empty(t, apiDiff.FuncsRemoved)
+	require.Len(t, apiDiff.PlatformsRemoved, 1)
+	assert.Equal(t, "Sync on windows/amd64", apiDiff.PlatformsRemoved[0].X)
+
+	incompatible := apiDiff.Incompatible()
+	require.Len(t, incompatible, 1)
+	assert.Equal(t, "Removed Platforms", incompatible[0].Kind)
+
+	// dropping a platform from the matrix is not a change of the API
+	newAPI["pkg/fs"] = APIPackage{
+		Funcs:     []string{"Open(string) -> (error)", "Sync()"},
+		Platforms: []string{"linux/amd64"},
+	}
+	assert.Empty(t, DiffAPI(oldAPI, newAPI).PlatformsRemoved)
+}
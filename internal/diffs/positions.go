@@ -0,0 +1,140 @@
+package diffs
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Links renders source positions as links to the file at the old or new commit.
+type Links struct {
+	// Template is a URL with {ref}, {file} and {line} placeholders, e.g.
+	// "https://github.com/org/repo/blob/{ref}/{file}#L{line}" (GitHub, Gitea: ".../src/commit/{ref}/{file}#L{line}",
+	// GitLab: ".../-/blob/{ref}/{file}#L{line}").
+	Template string
+	OldRef   string
+	NewRef   string
+
+	// OldDir and NewDir are the directories of the module relative to the repository root at the old and
+	// new ref, since positions are relative to the module root. They differ when the module moved.
+	OldDir string
+	NewDir string
+}
+
+// URL returns the link to a "file:line" position at the old or new ref.
+func (l *Links) URL(pos string, old bool) string {
+	file, line, ok := strings.Cut(pos, ":")
+	if !ok {
+		return ""
+	}
+	ref, dir := l.NewRef, l.NewDir
+	if old {
+		ref, dir = l.OldRef, l.OldDir
+	}
+	if dir != "" && dir != "." {
+		file = path.Join(dir, file)
+	}
+	return strings.NewReplacer("{ref}", ref, "{file}", file, "{line}", line).Replace(l.Template)
+}
+
+// withPosition appends a position to a rendered entry, as a link when a template is configured.
+// Removed entries point to the old ref, everything else to the new one.
+func (l *Links) withPosition(text, pos string, old bool) string {
+	if pos == "" {
+		return text
+	}
+	if l == nil || l.Template == "" {
+		return fmt.Sprintf("%s (`%s`)", text, pos)
+	}
+	return fmt.Sprintf("%s ([%s](%s))", text, pos, l.URL(pos, old))
+}
+
+// symbolPositions records where every exported symbol is declared, keyed like symbolKeys,
+// as "file:line" relative to the module root. Members declared outside the module, like methods
+// promoted from a dependency, are skipped.
+func symbolPositions(pkg *types.Package, fset *token.FileSet, root string) map[string]string {
+	positions := make(map[string]string)
+	record := func(key string, obj types.Object) {
+		p := fset.Position(obj.Pos())
+		if !p.IsValid() {
+			return
+		}
+		rel, err := filepath.Rel(root, p.Filename)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return
+		}
+		positions[key] = filepath.ToSlash(rel) + ":" + strconv.Itoa(p.Line)
+	}
+
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		if !token.IsExported(name) {
+			continue
+		}
+		obj := scope.Lookup(name)
+		record(name, obj)
+
+		o, ok := obj.(*types.TypeName)
+		if !ok || o.IsAlias() {
+			continue
+		}
+		switch u := o.Type().Underlying().(type) {
+		case *types.Struct:
+			for i := 0; i < u.NumFields(); i++ {
+				if f := u.Field(i); f.Exported() {
+					record(name+"."+f.Name(), f)
+				}
+			}
+		case *types.Interface:
+			for i := 0; i < u.NumMethods(); i++ {
+				if m := u.Method(i); m.Exported() {
+					record(name+"."+m.Name(), m)
+				}
+			}
+			continue
+		}
+		methods, _ := methodSets(o.Type())
+		for _, m := range methods {
+			record(name+"."+m.Name(), m)
+		}
+	}
+	return positions
+}
+
+// withPositions attaches positions to entries; members of a type are looked up as "Type.Name".
+func withPositions(items []APIDiffRes, positions map[string]string, tname string) []APIDiffRes {
+	for i := range items {
+		items[i].Pos = positions[memberKey(tname, symbolName(items[i].X))]
+	}
+	return items
+}
+
+// withTypePositions attaches the position of type tname to entries about the type as a whole,
+// e.g. its embedded fields, promoted members or the interfaces it implements.
+func withTypePositions(items []APIDiffRes, positions map[string]string, tname string) []APIDiffRes {
+	for i := range items {
+		items[i].Pos = positions[tname]
+	}
+	return items
+}
+
+// withChangePositions attaches the old and new positions to changed entries.
+func withChangePositions(items []APIDiffChange, oldPositions, newPositions map[string]string, tname string) []APIDiffChange {
+	for i := range items {
+		key := memberKey(tname, items[i].Name)
+		items[i].OldPos = oldPositions[key]
+		items[i].NewPos = newPositions[key]
+	}
+	return items
+}
+
+func memberKey(tname, name string) string {
+	if tname == "" {
+		return name
+	}
+	return tname + "." + name
+}
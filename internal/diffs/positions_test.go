@@ -0,0 +1,251 @@
+package diffs
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestSymbolPositions(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "/repo/api/client.go", `package api
+
+type Client struct {
+	Addr string
+	conn int
+}
+
+func (c *Client) Do() error { return nil }
+
+type Doer interface {
+	Do() error
+}
+
+func New() *Client { return nil }
+
+var Default = New()
+`, 0)
+	require.NoError(t, err)
+	pkg, err := (&types.Config{}).Check("example.com/m/api", fset, []*ast.File{f}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"Client":      "api/client.go:3",
+		"Client.Addr": "api/client.go:4",
+		"Client.Do":   "api/client.go:8",
+		"Doer":        "api/client.go:10",
+		"Doer.Do":     "api/client.go:11",
+		"New":         "api/client.go:14",
+		"Default":     "api/client.go:16",
+	}, symbolPositions(pkg, fset, "/repo"))
+
+	assert.Empty(t, symbolPositions(pkg, fset, "/elsewhere"))
+}
+
+func TestLinks(t *testing.T) {
+	github := &Links{Template: "https://github.com/o/r/blob/{ref}/{file}#L{line}", OldRef: "aaa", NewRef: "bbb"}
+	assert.Equal(t, "https://github.com/o/r/blob/aaa/api/client.go#L12", github.URL("api/client.go:12", true))
+	assert.Equal(t, "https://github.com/o/r/blob/bbb/api/client.go#L12", github.URL("api/client.go:12", false))
+
+	gitlab := &Links{Template: "https://gitlab.com/o/r/-/blob/{ref}/{file}#L{line}", OldRef: "aaa", NewRef: "bbb", OldDir: "gen", NewDir: "tools"}
+	assert.Equal(t, "https://gitlab.com/o/r/-/blob/bbb/tools/gen.go#L3", gitlab.URL("gen.go:3", false))
+	assert.Equal(t, "https://gitlab.com/o/r/-/blob/aaa/gen/gen.go#L3", gitlab.URL("gen.go:3", true), "a moved module links to its old dir")
+
+	assert.Equal(t, "`F` ([a.go:1](https://github.com/o/r/blob/aaa/a.go#L1))", github.withPosition("`F`", "a.go:1", true))
+	assert.Equal(t, "`F`", github.withPosition("`F`", "", true))
+
+	var none *Links
+	assert.Equal(t, "`F` (`a.go:1`)", none.withPosition("`F`", "a.go:1", false))
+}
+
+func TestAPIDiff_String_Links(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"example.com/m": {
+			Funcs:     []string{"Old() -> error", "Keep(int)"},
+			Types:     map[string]APIType{},
+			Positions: map[string]string{"Old": "m.go:10", "Keep": "m.go:20"},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"example.com/m": {
+			Funcs:     []string{"New() -> error", "Keep(string)"},
+			Types:     map[string]APIType{},
+			Positions: map[string]string{"New": "m.go:5", "Keep": "m.go:15"},
+		},
+	}
+
+	d := DiffAPI(oldAPI, newAPI)
+	d.Links = &Links{Template: "https://git.example.com/{ref}/{file}#L{line}", OldRef: "v1", NewRef: "v2"}
+	out := d.String()
+
+	// removals link to the old ref, additions and changes to the new one
+	assert.Contains(t, out, "Removed Funcs: `Old() -> error` ([m.go:10](https://git.example.com/v1/m.go#L10))")
+	assert.Contains(t, out, "    - New() -> error ([m.go:5](https://git.example.com/v2/m.go#L5))")
+	assert.Contains(t, out, "    - `Keep(int)` → `Keep(string)` ([m.go:15](https://git.example.com/v2/m.go#L15))")
+}
+
+// snapshotModule snapshots src as the only file of module example.com/p, with positions and docs.
+func snapshotModule(t *testing.T, src string) APIPackage {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "/mod/p.go", src, parser.ParseComments)
+	require.NoError(t, err)
+	tpkg, err := (&types.Config{}).Check("example.com/p", fset, []*ast.File{f}, nil)
+	require.NoError(t, err)
+	pkg := &packages.Package{
+		PkgPath: "example.com/p", Name: "p", Types: tpkg, Fset: fset, Syntax: []*ast.File{f},
+		Module: &packages.Module{Path: "example.com/p", Dir: "/mod"},
+	}
+	return snapshotPackages([]*packages.Package{pkg}, "example.com/p", &SnapshotOptions{Docs: true})["example.com/p"]
+}
+
+// TestDiffAPI_EveryChangeHasPosition fails when a kind of change is reported without the position of
+// what changed. A new kind of change must be added to the fixture, or the test fails as well.
+func TestDiffAPI_EveryChangeHasPosition(t *testing.T) {
+	oldPkg := snapshotModule(t, `package p
+
+// Deprecated: use Keep.
+func Gone() {}
+
+func Removed() {}
+
+func Changed(int) {}
+
+// Keep does things.
+func Keep() {}
+
+func Generic[T any](v T) {}
+
+var OldVar int
+var ChangedVar int
+
+const OldConst = 1
+const ChangedConst int = 1
+const Value = 1
+
+type Kind struct{}
+type ID int
+
+type Inner struct{ Deep int }
+
+type Config struct {
+	Name    string `+"`json:\"name\"`"+`
+	Removed int
+	Changed int
+	Inner
+}
+
+func (Config) Old()     {}
+func (Config) Move()    {}
+func (Config) Sig(int)  {}
+
+type Err struct{}
+
+func (Err) Error() string { return "" }
+
+type Box[T any] struct{ V T }
+
+type Node struct {
+	Value int
+	Next  *Node
+}
+`)
+	newPkg := snapshotModule(t, `package p
+
+func Added() {}
+
+func Changed(string) {}
+
+// Keep does other things.
+//
+// Deprecated: use Added.
+func Keep() {}
+
+func Generic[T comparable](v T) {}
+
+var NewVar int
+var ChangedVar string
+
+const NewConst = 1
+const ChangedConst int64 = 1
+const Value = 2
+
+type Kind interface{}
+type ID string
+
+type Extra struct{ More int }
+
+type Config struct {
+	Name    string `+"`json:\"title\"`"+`
+	Added   int
+	Changed string
+	Extra
+}
+
+func (Config) New()       {}
+func (*Config) Move()     {}
+func (Config) Sig(string) {}
+
+type Err struct{}
+
+func (Err) String() string { return "" }
+
+type Box[T comparable] struct{ V T }
+
+type Element struct {
+	Value int
+	Next  *Element
+}
+`)
+	oldPkg.Platforms = []string{"linux/amd64", "windows/amd64"}
+	oldPkg.SymbolPlatforms = map[string][]string{"Changed": {"linux/amd64"}}
+	newPkg.Platforms = []string{"linux/amd64", "windows/amd64"}
+	newPkg.SymbolPlatforms = map[string][]string{"ChangedVar": {"linux/amd64"}}
+
+	d := DiffAPI(map[string]APIPackage{"example.com/p": oldPkg}, map[string]APIPackage{"example.com/p": newPkg})
+
+	// entries about the package itself have no declaration to point at
+	packageLevel := func(s string) bool { return strings.HasPrefix(s, "package ") }
+
+	v := reflect.ValueOf(d).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		field := v.Field(i)
+		switch items := field.Interface().(type) {
+		case []APIDiffRes:
+			assert.NotEmpty(t, items, "%s: not covered by the fixture", name)
+			for _, r := range items {
+				assert.True(t, r.Pos != "" || packageLevel(r.X), "%s: %s has no position", name, r.X)
+			}
+		case []APIDiffChange:
+			assert.NotEmpty(t, items, "%s: not covered by the fixture", name)
+			for _, c := range items {
+				assert.True(t, c.OldPos != "" || c.NewPos != "" || c.Name == "", "%s: %s has no position", name, c.New)
+			}
+		case []APIRename:
+			assert.NotEmpty(t, items, "%s: not covered by the fixture", name)
+			for _, r := range items {
+				assert.True(t, r.OldPos != "" && r.NewPos != "", "%s: %s has no position", name, r.String())
+			}
+		case []APICompatChange:
+			for _, c := range items {
+				assert.True(t, c.Pos != "" || packageLevel(c.Symbol) || strings.HasSuffix(c.Kind, " Packages"),
+					"%s: %s %s has no position", name, c.Kind, c.Symbol)
+			}
+		}
+	}
+
+	d.Links = &Links{Template: "https://git.example.com/{ref}/{file}#L{line}", OldRef: "v1", NewRef: "v2"}
+	out := d.String()
+	assert.Contains(t, out, "`Err` no longer implements `error` ([p.go:")
+	assert.Contains(t, out, "`Gone`: Deprecated: use Keep. ([p.go:4](https://git.example.com/v1/p.go#L4))")
+	assert.Contains(t, out, "was renamed to `Element` (confidence")
+}
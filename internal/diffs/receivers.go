@@ -0,0 +1,80 @@
+package diffs
+
+import (
+	"fmt"
+	"go/types"
+)
+
+const (
+	receiverValue   = "value"
+	receiverPointer = "pointer"
+)
+
+// methodSets returns the exported methods of *T, which includes the methods of T,
+// and the receiver each one needs: "value" when it is also in the method set of T, "pointer" otherwise.
+func methodSets(t types.Type) (methods []*types.Func, receivers map[string]string) {
+	valueSet := types.NewMethodSet(t)
+	ptrSet := types.NewMethodSet(types.NewPointer(t))
+	for i := 0; i < ptrSet.Len(); i++ {
+		//nolint:errcheck
+		m := ptrSet.At(i).Obj().(*types.Func)
+		if !m.Exported() {
+			continue
+		}
+		if receivers == nil {
+			receivers = make(map[string]string)
+		}
+		methods = append(methods, m)
+		if valueSet.Lookup(m.Pkg(), m.Name()) != nil {
+			receivers[m.Name()] = receiverValue
+		} else {
+			receivers[m.Name()] = receiverPointer
+		}
+	}
+	return methods, receivers
+}
+
+// receiverDecl renders a method with its receiver, e.g. "(T) Close" or "(*T) Close".
+func receiverDecl(tname, method, receiver string) string {
+	if receiver == receiverPointer {
+		return fmt.Sprintf("(*%s) %s", tname, method)
+	}
+	return fmt.Sprintf("(%s) %s", tname, method)
+}
+
+// diffReceivers reports methods, present on both sides, whose receiver moved between T and *T.
+// Snapshots without receiver information are skipped.
+func diffReceivers(path, tname string, oldType, newType *APIType) []APIDiffChange {
+	var res []APIDiffChange
+	for name, newRecv := range newType.Receivers {
+		oldRecv, ok := oldType.Receivers[name]
+		if !ok || oldRecv == newRecv {
+			continue
+		}
+		res = append(res, APIDiffChange{
+			Label: fmt.Sprintf("Type `%s` Receivers", tname),
+			Path:  path,
+			Name:  name,
+			Old:   receiverDecl(tname, name, oldRecv),
+			New:   receiverDecl(tname, name, newRecv),
+		})
+	}
+	return res
+}
+
+// compatReceivers classifies receiver changes: a method leaving the value method set of T
+// breaks T values that were used to satisfy interfaces, or called through non-addressable values.
+// Moving a method from *T to T only grows the value method set.
+func compatReceivers(tname string, changed []APIDiffChange) []APICompatChange {
+	res := make([]APICompatChange, 0, len(changed))
+	for i := range changed {
+		c := &changed[i]
+		if c.New == receiverDecl(tname, c.Name, receiverPointer) {
+			res = append(res, compatChanged(c.Label, c, false, fmt.Sprintf(
+				"method left the value method set of %s; %s values no longer satisfy interfaces requiring it", tname, tname)))
+			continue
+		}
+		res = append(res, compatChanged(c.Label, c, true, fmt.Sprintf("method joined the value method set of %s", tname)))
+	}
+	return res
+}
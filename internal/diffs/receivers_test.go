@@ -0,0 +1,68 @@
+package diffs
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotTypes(t *testing.T, src string) map[string]APIPackage {
+	t.Helper()
+	pkg := typeCheck(t, src)
+	apkg := APIPackage{Types: make(map[string]APIType), TypeFacts: make(map[string]APITypeFacts)}
+	for _, name := range pkg.Scope().Names() {
+		if o, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok && o.Exported() {
+			apkg.Types[name] = snapshotType(o, &apkg)
+		}
+	}
+	return map[string]APIPackage{"example.com/p": apkg}
+}
+
+func TestSnapshotType_Receivers(t *testing.T) {
+	api := snapshotTypes(t, `package p
+
+type Conn struct{}
+
+func (Conn) Addr() string { return "" }
+func (*Conn) Close() error { return nil }
+func (*Conn) reset() {}
+`)
+	conn := api["example.com/p"].Types["Conn"]
+	assert.ElementsMatch(t, []string{"Addr() -> (string)", "Close() -> (error)"}, conn.Methods)
+	assert.Equal(t, map[string]string{"Addr": "value", "Close": "pointer"}, conn.Receivers)
+}
+
+func TestDiffAPI_Receivers(t *testing.T) {
+	oldAPI := snapshotTypes(t, `package p
+
+type Conn struct{}
+
+func (Conn) Addr() string { return "" }
+func (*Conn) Close() error { return nil }
+`)
+	newAPI := snapshotTypes(t, `package p
+
+type Conn struct{}
+
+func (*Conn) Addr() string { return "" }
+func (Conn) Close() error { return nil }
+`)
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	assert.Empty(t, apiDiff.MethodsAdded)
+	assert.Empty(t, apiDiff.MethodsRemoved)
+	require.Len(t, apiDiff.ReceiversChanged, 2)
+
+	byName := make(map[string]APICompatChange)
+	for _, c := range apiDiff.Compat {
+		byName[c.Symbol] = c
+	}
+	addr := byName["(Conn) Addr → (*Conn) Addr"]
+	assert.Equal(t, "Changed Type `Conn` Receivers", addr.Kind)
+	assert.False(t, addr.Compatible)
+	assert.True(t, byName["(*Conn) Close → (Conn) Close"].Compatible)
+
+	assert.Contains(t, apiDiff.String(), "`(Conn) Addr` → `(*Conn) Addr`")
+}
@@ -0,0 +1,235 @@
+package diffs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// minRenameConfidence is the lowest score a removed/added pair needs to be reported as a rename or move.
+const minRenameConfidence = 0.5
+
+// APIRename pairs a removed package-level symbol with an added one of the same kind and shape.
+type APIRename struct {
+	Kind       string  `json:"kind"` // Func, Var, Const or Type
+	OldPath    string  `json:"old_path"`
+	OldName    string  `json:"old_name"`
+	NewPath    string  `json:"new_path"`
+	NewName    string  `json:"new_name"`
+	Confidence float64 `json:"confidence"` // 0..1
+
+	OldPos string `json:"old_pos,omitempty"` // "file:line" relative to the module root
+	NewPos string `json:"new_pos,omitempty"`
+}
+
+func (r *APIRename) String() string {
+	var verb, target string
+	switch {
+	case r.OldPath == r.NewPath:
+		verb, target = "renamed to", "`"+r.NewName+"`"
+	case r.OldName == r.NewName:
+		verb, target = "moved to", "`"+r.NewPath+"`"
+	default:
+		verb, target = "moved and renamed to", "`"+r.NewPath+"."+r.NewName+"`"
+	}
+	return fmt.Sprintf("%s `%s.%s` was %s %s (confidence %.0f%%)", r.Kind, r.OldPath, r.OldName, verb, target, r.Confidence*100)
+}
+
+// renameCandidate is a package-level symbol that exists on one side only.
+type renameCandidate struct {
+	kind, path, name string
+	shape            string // signature or structure, with the package's own path and a type's own name masked
+	specific         bool   // false for shapes like "()" that many unrelated symbols share
+}
+
+// DetectRenames pairs symbols that disappeared with symbols that appeared, within or across packages,
+// when they have the same kind and an identical signature or structure. Each pair is scored by how
+// specific the shape is, how similar the names are, and how many other candidates share the shape.
+// Removed packages and added packages take part, so moving a type into a new package is found too.
+func DetectRenames(oldAPI, newAPI map[string]APIPackage) []APIRename {
+	removed := renameCandidates(oldAPI, newAPI)
+	added := renameCandidates(newAPI, oldAPI)
+
+	// shape -> number of added candidates with it, to penalize ambiguous matches
+	shared := make(map[string]int)
+	for _, a := range added {
+		shared[a.kind+"|"+a.shape]++
+	}
+
+	var pairs []APIRename
+	for _, r := range removed {
+		for _, a := range added {
+			if r.kind != a.kind || r.shape != a.shape {
+				continue
+			}
+			confidence := 0.6 + 0.4*nameSimilarity(r.name, a.name)
+			if !r.specific {
+				confidence -= 0.3
+			}
+			confidence -= 0.1 * float64(shared[a.kind+"|"+a.shape]-1)
+			if confidence < minRenameConfidence {
+				continue
+			}
+			pairs = append(pairs, APIRename{
+				Kind: r.kind, OldPath: r.path, OldName: r.name, NewPath: a.path, NewName: a.name,
+				Confidence: confidence,
+				OldPos:     oldAPI[r.path].Positions[r.name],
+				NewPos:     newAPI[a.path].Positions[a.name],
+			})
+		}
+	}
+
+	// best pairs first, and every symbol is used once
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Confidence != pairs[j].Confidence {
+			return pairs[i].Confidence > pairs[j].Confidence
+		}
+		return pairs[i].String() < pairs[j].String()
+	})
+	usedOld := make(map[string]bool)
+	usedNew := make(map[string]bool)
+	var res []APIRename
+	for _, p := range pairs {
+		oldKey, newKey := p.Kind+" "+p.OldPath+"."+p.OldName, p.Kind+" "+p.NewPath+"."+p.NewName
+		if usedOld[oldKey] || usedNew[newKey] {
+			continue
+		}
+		usedOld[oldKey], usedNew[newKey] = true, true
+		res = append(res, p)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].OldPath != res[j].OldPath {
+			return res[i].OldPath < res[j].OldPath
+		}
+		return res[i].OldName < res[j].OldName
+	})
+	return res
+}
+
+// selfPlaceholder replaces the name of a type candidate in its own shape, so that Next() *Old and Next() *New match.
+const selfPlaceholder = "@.<self>"
+
+// renameCandidates lists the package-level symbols of api that other does not have.
+// Shapes spell the package path as "@" and a type's references to itself as selfPlaceholder.
+func renameCandidates(api, other map[string]APIPackage) []renameCandidate {
+	var res []renameCandidate
+	for path, pkg := range api {
+		otherPkg := other[path]
+		mask := func(s string) string { return strings.ReplaceAll(s, path+".", "@.") }
+
+		for _, x := range pkg.Funcs {
+			name := symbolName(x)
+			if !containsName(otherPkg.Funcs, name) {
+				shape := strings.TrimPrefix(x, name)
+				res = append(res, renameCandidate{"Func", path, name, mask(shape), shape != "()"})
+			}
+		}
+		for _, x := range pkg.Vars {
+			name := symbolName(x)
+			if !containsName(otherPkg.Vars, name) {
+				res = append(res, renameCandidate{"Var", path, name, mask(strings.TrimPrefix(x, name)), true})
+			}
+		}
+		for _, x := range pkg.Consts {
+			name := symbolName(x)
+			if !containsName(otherPkg.Consts, name) {
+				shape := strings.TrimPrefix(x, name) + " = " + pkg.ConstValues[name]
+				res = append(res, renameCandidate{"Const", path, name, mask(shape), true})
+			}
+		}
+		for tname, t := range pkg.Types {
+			if _, ok := otherPkg.Types[tname]; ok {
+				continue
+			}
+			self := regexp.MustCompile(`@\.` + regexp.QuoteMeta(tname) + `\b`)
+			shape := self.ReplaceAllLiteralString(mask(typeShape(&t)), selfPlaceholder)
+			res = append(res, renameCandidate{"Type", path, tname, shape, len(t.Fields)+len(t.Methods) > 0 || t.Kind != "struct"})
+		}
+	}
+	return res
+}
+
+// typeShape describes a type by its kind, exported fields and methods, or by its underlying type
+// when it has neither.
+func typeShape(t *APIType) string {
+	if len(t.Fields)+len(t.Methods) == 0 {
+		return fmt.Sprintf("%s alias=%t %s", t.Kind, t.Alias, t.Underlying)
+	}
+	fields := append([]string{}, t.Fields...)
+	methods := append([]string{}, t.Methods...)
+	sort.Strings(fields)
+	sort.Strings(methods)
+	return fmt.Sprintf("%s alias=%t {%s} {%s}", t.Kind, t.Alias, strings.Join(fields, "; "), strings.Join(methods, "; "))
+}
+
+// typeRenamer returns a func that spells the types renamed or moved in renames with their new names,
+// e.g. "New() -> (*example.com/p.Old)" as "New() -> (*example.com/p.Element)".
+func typeRenamer(renames []APIRename) func(string) string {
+	var olds []string
+	news := make(map[string]string)
+	for i := range renames {
+		if r := &renames[i]; r.Kind == "Type" {
+			oldName := r.OldPath + "." + r.OldName
+			olds = append(olds, regexp.QuoteMeta(oldName))
+			news[oldName] = r.NewPath + "." + r.NewName
+		}
+	}
+	if len(olds) == 0 {
+		return func(s string) string { return s }
+	}
+	re := regexp.MustCompile(`(^|[^\w./-])(` + strings.Join(olds, "|") + `)\b`)
+	return func(s string) string {
+		return re.ReplaceAllStringFunc(s, func(m string) string {
+			sub := re.FindStringSubmatch(m)
+			return sub[1] + news[sub[2]]
+		})
+	}
+}
+
+// withoutTypeRenames drops changed entries that only differ in the name of a renamed or moved type,
+// e.g. New() -> (*Old) and New() -> (*Element): the rename itself is what breaks users, and it is reported.
+func withoutTypeRenames(changed []APIDiffChange, rename func(string) string) []APIDiffChange {
+	res := changed[:0]
+	for _, c := range changed {
+		if rename(c.Old) != c.New {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// nameSimilarity scores two identifiers from 0 to 1 by their longest common subsequence, ignoring case.
+func nameSimilarity(a, b string) float64 {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	if a == b {
+		return 1
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				cur[j] = prev[j-1] + 1
+			case prev[j] >= cur[j-1]:
+				cur[j] = prev[j]
+			default:
+				cur[j] = cur[j-1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return 2 * float64(prev[len(b)]) / float64(len(a)+len(b))
+}
+
+// writeRenamesSection lists renamed and moved symbols.
+func writeRenamesSection(sb *strings.Builder, links *Links, renames []APIRename) {
+	if len(renames) == 0 {
+		return
+	}
+	sb.WriteString("\n### Renamed and Moved\n\n")
+	for i := range renames {
+		sb.WriteString("- " + links.withPosition(renames[i].String(), renames[i].NewPos, false) + "\n")
+	}
+}
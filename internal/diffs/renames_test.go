@@ -0,0 +1,162 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectRenames(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"example.com/m/pkg/a": {
+			Funcs: []string{"NewClient(string, int) -> (*example.com/m/pkg/a.Client, error)", "Start()"},
+			Types: map[string]APIType{
+				"Client": {Kind: "struct", Fields: []string{"Addr string"}, Methods: []string{"Do() -> error"}},
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"example.com/m/pkg/a": {
+			Funcs: []string{"NewHTTPClient(string, int) -> (*example.com/m/pkg/a.Client, error)", "Run()"},
+			Types: map[string]APIType{
+				"Client": {Kind: "struct", Fields: []string{"Addr string"}, Methods: []string{"Do() -> error"}},
+			},
+		},
+		"example.com/m/pkg/b": {
+			Types: map[string]APIType{},
+		},
+	}
+	// move Client from a to b, along with the self-reference in the constructor
+	oldAPI["example.com/m/pkg/a"].Types["Options"] = APIType{Kind: "struct", Fields: []string{"Timeout int", "Next *example.com/m/pkg/a.Options"}}
+	newAPI["example.com/m/pkg/b"].Types["Options"] = APIType{Kind: "struct", Fields: []string{"Timeout int", "Next *example.com/m/pkg/b.Options"}}
+
+	renames := DetectRenames(oldAPI, newAPI)
+	require.Len(t, renames, 2)
+
+	assert.Equal(t, "Func", renames[0].Kind)
+	assert.Equal(t, "NewClient", renames[0].OldName)
+	assert.Equal(t, "NewHTTPClient", renames[0].NewName)
+	assert.InDelta(t, 0.6+0.4*18.0/22.0, renames[0].Confidence, 1e-9)
+	assert.Equal(t, "Func `example.com/m/pkg/a.NewClient` was renamed to `NewHTTPClient` (confidence 93%)", renames[0].String())
+
+	assert.Equal(t, "Type `example.com/m/pkg/a.Options` was moved to `example.com/m/pkg/b` (confidence 100%)", renames[1].String())
+}
+
+func TestDetectRenames_SelfReferentialType(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"example.com/m": {Types: map[string]APIType{
+			"Node": {Kind: "struct", Fields: []string{"Value int", "Parent *example.com/m.Node"}, Methods: []string{
+				"Clone() -> (example.com/m.Node)", "Next() -> (*example.com/m.Node)", "Nodes() -> ([]example.com/m.Node)",
+			}},
+			"NodeList": {Kind: "slice", Underlying: "[]example.com/m.Node"},
+		}},
+	}
+	newAPI := map[string]APIPackage{
+		"example.com/m": {Types: map[string]APIType{
+			"Element": {Kind: "struct", Fields: []string{"Value int", "Parent *example.com/m.Element"}, Methods: []string{
+				"Clone() -> (example.com/m.Element)", "Next() -> (*example.com/m.Element)", "Nodes() -> ([]example.com/m.Element)",
+			}},
+			"NodeList": {Kind: "slice", Underlying: "[]example.com/m.Element"},
+		}},
+	}
+
+	renames := DetectRenames(oldAPI, newAPI)
+	require.Len(t, renames, 1)
+	assert.Equal(t, "Node", renames[0].OldName)
+	assert.Equal(t, "Element", renames[0].NewName)
+
+	// other types named alike are not masked: NodeList is not a reference of Node to itself
+	shape := renameCandidates(map[string]APIPackage{"p": {Types: map[string]APIType{
+		"Node": {Kind: "struct", Fields: []string{"Next *p.Node", "All p.NodeList"}},
+	}}}, nil)[0].shape
+	assert.Equal(t, "struct alias=false {All @.NodeList; Next *@.<self>} {}", shape)
+}
+
+func TestDetectRenames_Ambiguous(t *testing.T) {
+	oldAPI := map[string]APIPackage{"p": {Vars: []string{"ErrA error"}}}
+	newAPI := map[string]APIPackage{"p": {Vars: []string{"ErrB error", "ErrC error", "ErrD error"}}}
+
+	renames := DetectRenames(oldAPI, newAPI)
+	require.Len(t, renames, 1)
+	// three candidates share the shape
+	assert.InDelta(t, 0.6+0.4*0.75-0.2, renames[0].Confidence, 1e-9)
+	assert.Equal(t, "ErrB", renames[0].NewName)
+
+	// a bare "()" is not evidence enough on its own
+	assert.Empty(t, DetectRenames(
+		map[string]APIPackage{"p": {Funcs: []string{"Start()"}}},
+		map[string]APIPackage{"p": {Funcs: []string{"Run()"}}},
+	))
+}
+
+func TestNameSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, nameSimilarity("Client", "client"), 1e-9)
+	assert.InDelta(t, 18.0/22.0, nameSimilarity("NewClient", "NewHTTPClient"), 1e-9)
+	assert.InDelta(t, 0.0, nameSimilarity("abc", "xyz"), 1e-9)
+}
+
+func TestAPIDiff_String_Renames(t *testing.T) {
+	d := DiffAPI(
+		map[string]APIPackage{"p": {Funcs: []string{"Old(int) -> error"}, Types: map[string]APIType{}}},
+		map[string]APIPackage{"p": {Funcs: []string{"New(int) -> error"}, Types: map[string]APIType{}}},
+	)
+	out := d.String()
+	assert.Contains(t, out, "- [Renamed and Moved](#renamed-and-moved)\n")
+	assert.Contains(t, out, "\n### Renamed and Moved\n\n- Func `p.Old` was renamed to `New` (confidence 60%)\n")
+}
+
+func TestDiffAPI_RenamedTypeInSignatures(t *testing.T) {
+	oldAPI := snapshotSource(t, `package p
+
+type Node struct {
+	Value int
+	Next  *Node
+}
+
+func New() *Node { return nil }
+
+func Find(id int) *Node { return nil }
+
+var Default *Node
+
+type Tree struct{ Root *Node }
+
+func (Tree) First() *Node { return nil }
+
+type Nodes []Node
+`)
+	newAPI := snapshotSource(t, `package p
+
+type Element struct {
+	Value int
+	Next  *Element
+}
+
+func New() *Element { return nil }
+
+func Find(id string) *Element { return nil }
+
+var Default *Element
+
+type Tree struct{ Root *Element }
+
+func (Tree) First() *Element { return nil }
+
+type Nodes []Element
+`)
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	require.Len(t, apiDiff.Renames, 1)
+	assert.Equal(t, "Element", apiDiff.Renames[0].NewName)
+
+	// only the change beyond the rename is reported
+	require.Len(t, apiDiff.FuncsChanged, 1)
+	assert.Equal(t, "Find", apiDiff.FuncsChanged[0].Name)
+	assert.Empty(t, apiDiff.VarsChanged)
+	assert.Empty(t, apiDiff.FieldsChanged)
+	assert.Empty(t, apiDiff.MethodsChanged)
+	assert.Empty(t, apiDiff.TypesUnderlyingChanged)
+	for _, c := range apiDiff.Compat {
+		assert.NotContains(t, c.Symbol, "New", "only the rename itself breaks callers of New")
+	}
+}
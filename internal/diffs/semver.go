@@ -0,0 +1,142 @@
+package diffs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+)
+
+// VersionSuggestion is the next semantic version derived from an API diff.
+type VersionSuggestion struct {
+	Current  string   `json:"current"`
+	Next     string   `json:"next"`
+	Bump     string   `json:"bump"`
+	Reasons  []string `json:"reasons"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (v *VersionSuggestion) String() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Suggested version: %s (%s bump from %s)\n", v.Next, v.Bump, v.Current))
+
+	if len(v.Reasons) > 0 {
+		sb.WriteString("\nReasons:\n")
+		for _, r := range v.Reasons {
+			sb.WriteString("- " + r + "\n")
+		}
+	}
+	if len(v.Warnings) > 0 {
+		sb.WriteString("\nWarnings:\n")
+		for _, w := range v.Warnings {
+			sb.WriteString("- " + w + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// SuggestVersion picks the next version after current:
+// incompatible changes mean major (minor while on v0), additions mean minor, anything else patch,
+// including compatible changes to existing symbols. A prerelease is followed by its own release when
+// that is already the bump, e.g. v1.2.0-rc.1 by v1.2.0; build metadata is ignored.
+// modulePath is the module path of the new tree, checked for the /vN suffix a major bump needs.
+func SuggestVersion(current string, d *APIDiff, modulePath string) (VersionSuggestion, error) {
+	if !semver.IsValid(current) {
+		return VersionSuggestion{}, fmt.Errorf("not a semantic version: %q", current)
+	}
+	major, minor, patch, err := parseVersionCore(current)
+	if err != nil {
+		return VersionSuggestion{}, err
+	}
+
+	prerelease := semver.Prerelease(current) != ""
+	res := VersionSuggestion{Current: current}
+
+	var incompatible, additions, compatible []string
+	for _, c := range d.Compat {
+		line := fmt.Sprintf("`%s` %s: `%s` (%s)", c.Path, c.Kind, c.Symbol, c.Reason)
+		switch {
+		case !c.Compatible:
+			incompatible = append(incompatible, line)
+		case strings.HasPrefix(c.Kind, "Added "):
+			additions = append(additions, line)
+		default:
+			// e.g. a parameter widened to an interface: no new API to warrant a minor version
+			compatible = append(compatible, line)
+		}
+	}
+
+	switch {
+	case len(incompatible) > 0 && major == 0:
+		res.Bump = BumpMinor
+		res.Reasons = append([]string{"breaking changes while on v0 are released as a minor version"}, incompatible...)
+	case len(incompatible) > 0:
+		res.Bump = BumpMajor
+		res.Reasons = incompatible
+	case len(additions) > 0:
+		res.Bump = BumpMinor
+		res.Reasons = additions
+	case len(compatible) > 0:
+		res.Bump = BumpPatch
+		res.Reasons = compatible
+	default:
+		res.Bump = BumpPatch
+		res.Reasons = []string{"no exported API changes"}
+	}
+	res.Next = nextVersion(major, minor, patch, prerelease, res.Bump)
+
+	if nextMajor, _, _, _ := parseVersionCore(res.Next); res.Bump == BumpMajor && nextMajor >= 2 {
+		suffix := fmt.Sprintf("/v%d", nextMajor)
+		if !strings.HasSuffix(modulePath, suffix) {
+			res.Warnings = append(res.Warnings, fmt.Sprintf(
+				"module path %q lacks the %s suffix required to release %s", modulePath, suffix, res.Next))
+		}
+	}
+
+	return res, nil
+}
+
+// nextVersion returns the version after major.minor.patch for bump. A prerelease comes before the
+// release of the same version, so that release is next when the bump does not go past it:
+// v1.2.3-rc.1 is followed by v1.2.3 on a patch bump, v1.3.0-rc.1 by v1.3.0 on a minor bump too.
+func nextVersion(major, minor, patch int, prerelease bool, bump string) string {
+	switch bump {
+	case BumpMajor:
+		if !prerelease || minor != 0 || patch != 0 {
+			major, minor, patch = major+1, 0, 0
+		}
+	case BumpMinor:
+		if !prerelease || patch != 0 {
+			minor, patch = minor+1, 0
+		}
+	default:
+		if !prerelease {
+			patch++
+		}
+	}
+	return fmt.Sprintf("v%d.%d.%d", major, minor, patch)
+}
+
+// parseVersionCore returns the numeric parts of a valid semver, ignoring prerelease and build suffixes.
+func parseVersionCore(v string) (major, minor, patch int, err error) {
+	core := strings.TrimPrefix(semver.Canonical(v), "v")
+	core = strings.TrimSuffix(core, semver.Prerelease(v))
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("cannot parse version %q", v)
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		if nums[i], err = strconv.Atoi(p); err != nil {
+			return 0, 0, 0, fmt.Errorf("cannot parse version %q: %w", v, err)
+		}
+	}
+	return nums[0], nums[1], nums[2], nil
+}
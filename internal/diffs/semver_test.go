@@ -0,0 +1,63 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestVersion(t *testing.T) {
+	breaking := &APIDiff{Compat: []APICompatChange{
+		{Path: "pkg/a", Kind: "Removed Funcs", Symbol: "Foo()", Reason: "callers of the function no longer compile"},
+	}}
+	additive := &APIDiff{Compat: []APICompatChange{
+		{Path: "pkg/a", Kind: "Added Funcs", Symbol: "Bar()", Compatible: true, Reason: "new function"},
+	}}
+	compatibleOnly := &APIDiff{Compat: []APICompatChange{
+		{Path: "pkg/a", Kind: "Changed Funcs", Symbol: "Use(*bytes.Buffer) → Use(io.Writer)", Compatible: true, Reason: "existing calls still type-check"},
+	}}
+	empty := &APIDiff{}
+
+	tests := []struct {
+		name       string
+		current    string
+		diff       *APIDiff
+		modulePath string
+		next       string
+		bump       string
+		warns      int
+	}{
+		{"breaking on v1", "v1.2.3", breaking, "example.com/m", "v2.0.0", BumpMajor, 1},
+		{"breaking on v1 with suffix", "v1.2.3", breaking, "example.com/m/v2", "v2.0.0", BumpMajor, 0},
+		{"breaking on v0", "v0.4.1", breaking, "example.com/m", "v0.5.0", BumpMinor, 0},
+		{"additions", "v1.2.3", additive, "example.com/m", "v1.3.0", BumpMinor, 0},
+		{"compatible changes only", "v1.2.3", compatibleOnly, "example.com/m", "v1.2.4", BumpPatch, 0},
+		{"nothing", "v1.2.3", empty, "example.com/m", "v1.2.4", BumpPatch, 0},
+		{"prerelease", "v1.2.3-rc.1", empty, "example.com/m", "v1.2.3", BumpPatch, 0},
+		{"prerelease of a minor, compatible changes", "v1.2.0-rc.1", compatibleOnly, "example.com/m", "v1.2.0", BumpPatch, 0},
+		{"prerelease of a minor, additions", "v1.2.0-rc.1", additive, "example.com/m", "v1.2.0", BumpMinor, 0},
+		{"prerelease of a patch, additions", "v1.2.3-rc.1", additive, "example.com/m", "v1.3.0", BumpMinor, 0},
+		{"prerelease of a major, breaking", "v2.0.0-beta.2", breaking, "example.com/m/v2", "v2.0.0", BumpMajor, 0},
+		{"prerelease of a minor, breaking", "v1.2.0-rc.1", breaking, "example.com/m/v2", "v2.0.0", BumpMajor, 0},
+		{"prerelease on v0, breaking", "v0.5.0-rc.1", breaking, "example.com/m", "v0.5.0", BumpMinor, 0},
+		{"build metadata", "v1.2.3+build.7", compatibleOnly, "example.com/m", "v1.2.4", BumpPatch, 0},
+		{"prerelease with build metadata", "v1.2.0-rc.1+build.7", additive, "example.com/m", "v1.2.0", BumpMinor, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := SuggestVersion(tt.current, tt.diff, tt.modulePath)
+			require.NoError(t, err)
+			assert.Equal(t, tt.next, s.Next)
+			assert.Equal(t, tt.bump, s.Bump)
+			assert.Len(t, s.Warnings, tt.warns)
+			assert.NotEmpty(t, s.Reasons)
+		})
+	}
+}
+
+func TestSuggestVersion_InvalidTag(t *testing.T) {
+	_, err := SuggestVersion("release-1", &APIDiff{}, "example.com/m")
+	require.Error(t, err)
+}
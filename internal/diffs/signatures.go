@@ -0,0 +1,109 @@
+package diffs
+
+import (
+	"bytes"
+	"go/types"
+)
+
+// signatureString renders the types of a signature, e.g. "(int, ...string) -> (error)".
+// Parameter and result names are left out, so that renaming them is not reported as a change.
+func signatureString(sig *types.Signature) string {
+	var b bytes.Buffer
+	b.WriteString("(")
+	for i := 0; i < sig.Params().Len(); i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		t := sig.Params().At(i).Type()
+		if s, ok := t.(*types.Slice); ok && sig.Variadic() && i == sig.Params().Len()-1 {
+			b.WriteString("..." + s.Elem().String())
+			continue
+		}
+		b.WriteString(t.String())
+	}
+	b.WriteString(")")
+	if sig.Results().Len() > 0 {
+		b.WriteString(" -> (")
+		for i := 0; i < sig.Results().Len(); i++ {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(sig.Results().At(i).Type().String())
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// goDecl renders a func or method the way gofmt would, with parameter and result names,
+// and with types qualified by package name relative to pkg, e.g. "func (c *Conn) Write(p []byte) (n int, err error)".
+// Interface methods are rendered as they appear in the interface body.
+func goDecl(fn *types.Func, pkg *types.Package) string {
+	qf := func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	}
+	//nolint:errcheck
+	sig := fn.Type().(*types.Signature)
+
+	var b bytes.Buffer
+	if recv := sig.Recv(); recv != nil {
+		if types.IsInterface(recv.Type()) {
+			b.WriteString(fn.Name())
+			types.WriteSignature(&b, sig, qf)
+			return b.String()
+		}
+		b.WriteString("func (")
+		if recv.Name() != "" && recv.Name() != "_" {
+			b.WriteString(recv.Name() + " ")
+		}
+		b.WriteString(types.TypeString(recv.Type(), qf) + ") ")
+	} else {
+		b.WriteString("func ")
+	}
+	b.WriteString(fn.Name())
+	types.WriteSignature(&b, sig, qf)
+	return b.String()
+}
+
+// withDecls attaches Go declarations to added or removed entries, looked up by symbol name.
+func withDecls(items []APIDiffRes, decls map[string]string) []APIDiffRes {
+	for i := range items {
+		items[i].Decl = decls[symbolName(items[i].X)]
+	}
+	return items
+}
+
+// withChangeDecls attaches the old and new Go declarations to changed entries.
+func withChangeDecls(items []APIDiffChange, oldDecls, newDecls map[string]string) []APIDiffChange {
+	for i := range items {
+		items[i].OldDecl = oldDecls[items[i].Name]
+		items[i].NewDecl = newDecls[items[i].Name]
+	}
+	return items
+}
+
+// display renders an entry as Go code when requested and a declaration is known.
+func (r *APIDiffRes) display(goSyntax bool) string {
+	if goSyntax && r.Decl != "" {
+		return r.Decl
+	}
+	return r.X
+}
+
+func (c *APIDiffChange) display(goSyntax bool) (oldX, newX string) {
+	if goSyntax && c.OldDecl != "" && c.NewDecl != "" {
+		return c.OldDecl, c.NewDecl
+	}
+	return c.Old, c.New
+}
+
+func setDecl(decls map[string]string, name, decl string) map[string]string {
+	if decls == nil {
+		decls = make(map[string]string)
+	}
+	decls[name] = decl
+	return decls
+}
@@ -0,0 +1,98 @@
+package diffs
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureString_Variadic(t *testing.T) {
+	pkg := typeCheck(t, `package p
+
+func F(n int, opts ...string) error { return nil }
+func G(n int, opts []string) error { return nil }
+`)
+	//nolint:errcheck
+	f := pkg.Scope().Lookup("F").Type().(*types.Signature)
+	//nolint:errcheck
+	g := pkg.Scope().Lookup("G").Type().(*types.Signature)
+	assert.Equal(t, "(int, ...string) -> (error)", signatureString(f))
+	assert.Equal(t, "(int, []string) -> (error)", signatureString(g))
+}
+
+func TestGoDecl(t *testing.T) {
+	pkg := typeCheck(t, `package p
+
+type Conn struct{}
+
+func (c *Conn) Write(p []byte) (n int, err error) { return 0, nil }
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+func Copy(dst *Conn, src Reader, opts ...string) error { return nil }
+
+func Map[K comparable, V any](m map[K]V) []K { return nil }
+`)
+	//nolint:errcheck
+	copyFn := pkg.Scope().Lookup("Copy").(*types.Func)
+	assert.Equal(t, "func Copy(dst *Conn, src Reader, opts ...string) error", goDecl(copyFn, pkg))
+
+	//nolint:errcheck
+	mapFn := pkg.Scope().Lookup("Map").(*types.Func)
+	assert.Equal(t, "func Map[K comparable, V any](m map[K]V) []K", goDecl(mapFn, pkg))
+
+	conn := pkg.Scope().Lookup("Conn").Type()
+	write, _, _ := types.LookupFieldOrMethod(conn, true, pkg, "Write")
+	//nolint:errcheck
+	assert.Equal(t, "func (c *Conn) Write(p []byte) (n int, err error)", goDecl(write.(*types.Func), pkg))
+
+	reader := pkg.Scope().Lookup("Reader").Type()
+	read, _, _ := types.LookupFieldOrMethod(reader, false, pkg, "Read")
+	//nolint:errcheck
+	assert.Equal(t, "Read(p []byte) (int, error)", goDecl(read.(*types.Func), pkg))
+}
+
+func TestDiffAPI_GoSyntax(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs: []string{"Join(...string) -> (string)", "Open(string) -> (error)"},
+			Decls: map[string]string{
+				"Join": "func Join(parts ...string) string",
+				"Open": "func Open(name string) error",
+			},
+			Signatures: map[string]APISignature{
+				"Join": {Params: []string{"[]string"}, Results: []string{"string"}, Variadic: true},
+				"Open": {Params: []string{"string"}, Results: []string{"error"}},
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Funcs: []string{"Join([]string) -> (string)", "Open(string) -> (error)"},
+			Decls: map[string]string{
+				"Join": "func Join(parts []string) string",
+				"Open": "func Open(path string) error", // renamed parameter only
+			},
+			Signatures: map[string]APISignature{
+				"Join": {Params: []string{"[]string"}, Results: []string{"string"}},
+				"Open": {Params: []string{"string"}, Results: []string{"error"}},
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	require.Len(t, apiDiff.FuncsChanged, 1)
+	assert.Equal(t, "Join", apiDiff.FuncsChanged[0].Name)
+
+	incompatible := apiDiff.Incompatible()
+	require.Len(t, incompatible, 1)
+	assert.Equal(t, "parameter 1 type changed from ...string to []string", incompatible[0].Reason)
+
+	assert.Contains(t, apiDiff.String(), "`Join(...string) -> (string)` → `Join([]string) -> (string)`")
+	apiDiff.GoSyntax = true
+	assert.Contains(t, apiDiff.String(), "`func Join(parts ...string) string` → `func Join(parts []string) string`")
+}
@@ -0,0 +1,95 @@
+package diffs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SnapshotFileSchema is the version of the SnapshotFile layout, including APIPackage. It is bumped
+// whenever a field is renamed or removed, or changes meaning; adding a field does not bump it.
+const SnapshotFileSchema = 1
+
+// SnapshotFile is an API snapshot exported by 'relimpact snapshot', to be diffed later with
+// 'relimpact diff-api' without checking the refs out again.
+type SnapshotFile struct {
+	Schema    int    `json:"schema"`
+	Relimpact string `json:"relimpact"`  // version of relimpact that took the snapshot
+	GoVersion string `json:"go_version"` // go command the packages were loaded with
+	Context   string `json:"context"`    // GOOS, GOARCH, GOFLAGS, CGO_ENABLED and the snapshot options
+
+	Module  string    `json:"module"`
+	Ref     string    `json:"ref"`    // as given on the command line, e.g. v1.2.0
+	Commit  string    `json:"commit"` // the SHA the ref resolved to
+	Created time.Time `json:"created"`
+
+	// API is keyed by package import path, as returned by SnapshotAPI.
+	API map[string]APIPackage `json:"api"`
+}
+
+// NewSnapshotFile snapshots the module checked out in dir, at ref.
+func NewSnapshotFile(dir, ref string, opts SnapshotOptions) *SnapshotFile {
+	header := newCacheHeader(dir, &opts)
+	return &SnapshotFile{
+		Schema:    SnapshotFileSchema,
+		Relimpact: header.Relimpact,
+		GoVersion: header.GoVersion,
+		Context:   header.Context,
+		Module:    getModulePath(dir),
+		Ref:       ref,
+		Commit:    getGitCommitSHA(dir),
+		Created:   time.Now().UTC(),
+		API:       SnapshotAPIWith(dir, opts),
+	}
+}
+
+// WriteSnapshotFile writes f to path as indented JSON, or to stdout when path is "-".
+func WriteSnapshotFile(path string, f *SnapshotFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// ReadSnapshotFile reads a snapshot written by WriteSnapshotFile, rejecting other schema versions.
+func ReadSnapshotFile(path string) (*SnapshotFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f SnapshotFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch {
+	case f.Schema == 0:
+		return nil, fmt.Errorf("%s: not a relimpact snapshot (no schema version)", path)
+	case f.Schema != SnapshotFileSchema:
+		return nil, fmt.Errorf("%s: snapshot schema %d, this relimpact reads %d; re-export it with relimpact %s",
+			path, f.Schema, SnapshotFileSchema, f.Relimpact)
+	case f.API == nil:
+		return nil, fmt.Errorf("%s: snapshot has no api", path)
+	}
+	return &f, nil
+}
+
+// Mismatch explains why old and new snapshots are not comparable like for like, or returns "".
+// They can still be diffed, but changes may come from the environment rather than the code.
+func (f *SnapshotFile) Mismatch(other *SnapshotFile) string {
+	switch {
+	case f.Module != other.Module:
+		return fmt.Sprintf("module %s vs %s", f.Module, other.Module)
+	case f.Context != other.Context:
+		return fmt.Sprintf("build context %q vs %q", f.Context, other.Context)
+	case f.GoVersion != other.GoVersion:
+		return fmt.Sprintf("go %s vs %s", f.GoVersion, other.GoVersion)
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package diffs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSnapshotFile(funcs ...string) *SnapshotFile {
+	return &SnapshotFile{
+		Schema:    SnapshotFileSchema,
+		Relimpact: "v1.0.0",
+		GoVersion: "go1.24.0",
+		Context:   "goos=linux;goarch=amd64",
+		Module:    "example.com/m",
+		Ref:       "v1.0.0",
+		Commit:    "0123456789abcdef0123456789abcdef01234567",
+		Created:   time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		API:       map[string]APIPackage{"example.com/m": {Funcs: funcs, Types: map[string]APIType{}}},
+	}
+}
+
+func TestSnapshotFile_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, newPath := filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json")
+	require.NoError(t, WriteSnapshotFile(oldPath, testSnapshotFile("Get() -> (error)", "Put()")))
+	require.NoError(t, WriteSnapshotFile(newPath, testSnapshotFile("Get() -> (error)")))
+
+	oldFile, err := ReadSnapshotFile(oldPath)
+	require.NoError(t, err)
+	assert.Equal(t, testSnapshotFile("Get() -> (error)", "Put()"), oldFile)
+	newFile, err := ReadSnapshotFile(newPath)
+	require.NoError(t, err)
+	assert.Empty(t, oldFile.Mismatch(newFile))
+
+	d := DiffAPI(oldFile.API, newFile.API)
+	require.Len(t, d.FuncsRemoved, 1)
+	assert.Equal(t, "example.com/m", d.FuncsRemoved[0].Path)
+	assert.Equal(t, "Put()", d.FuncsRemoved[0].X)
+}
+
+func TestReadSnapshotFile_Schema(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	_, err := ReadSnapshotFile(write("future.json", `{"schema":99,"relimpact":"v9.0.0","api":{}}`))
+	assert.ErrorContains(t, err, "snapshot schema 99, this relimpact reads 1; re-export it with relimpact v9.0.0")
+
+	// the bare map the cache used to hold is not a snapshot file
+	_, err = ReadSnapshotFile(write("bare.json", `{"example.com/m":{"funcs":["F()"]}}`))
+	assert.ErrorContains(t, err, "not a relimpact snapshot")
+
+	_, err = ReadSnapshotFile(write("empty.json", `{"schema":1}`))
+	assert.ErrorContains(t, err, "snapshot has no api")
+}
+
+func TestSnapshotFile_Mismatch(t *testing.T) {
+	linux, windows := testSnapshotFile(), testSnapshotFile()
+	windows.Context = "goos=windows;goarch=amd64"
+	assert.Equal(t, `build context "goos=linux;goarch=amd64" vs "goos=windows;goarch=amd64"`, linux.Mismatch(windows))
+}
@@ -0,0 +1,239 @@
+package diffs
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// symbolDocs collects the doc comments of exported symbols, keyed like symbolKeys
+// ("Type.Name" for fields and methods), and "" for the package itself.
+func symbolDocs(files []*ast.File) map[string]string {
+	docs := make(map[string]string)
+	add := func(key string, groups ...*ast.CommentGroup) {
+		for _, g := range groups {
+			if g != nil {
+				docs[key] = g.Text()
+				return
+			}
+		}
+	}
+
+	for _, f := range files {
+		// the package doc belongs in one file; when several files have one, keep the first
+		if _, ok := docs[""]; !ok {
+			add("", f.Doc)
+		}
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if !d.Name.IsExported() {
+					continue
+				}
+				if d.Recv == nil {
+					add(d.Name.Name, d.Doc)
+				} else if recv := receiverTypeName(d.Recv); recv != "" {
+					add(recv+"."+d.Name.Name, d.Doc)
+				}
+			case *ast.GenDecl:
+				// the doc comment of an ungrouped declaration is attached to the GenDecl
+				var groupDoc *ast.CommentGroup
+				if !d.Lparen.IsValid() {
+					groupDoc = d.Doc
+				}
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						if !s.Name.IsExported() {
+							continue
+						}
+						add(s.Name.Name, s.Doc, groupDoc)
+						addMemberDocs(s, add)
+					case *ast.ValueSpec:
+						for _, name := range s.Names {
+							if name.IsExported() {
+								add(name.Name, s.Doc, groupDoc)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return docs
+}
+
+// addMemberDocs records the doc comments of struct fields and interface methods.
+func addMemberDocs(s *ast.TypeSpec, add func(key string, docs ...*ast.CommentGroup)) {
+	var fields *ast.FieldList
+	switch t := s.Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields = t.Methods
+	}
+	if fields == nil {
+		return
+	}
+	for _, field := range fields.List {
+		for _, name := range field.Names {
+			if name.IsExported() {
+				add(s.Name.Name+"."+name.Name, field.Doc)
+			}
+		}
+	}
+}
+
+// receiverTypeName returns "T" for receivers like (t T), (t *T) and (t *T[K, V]).
+func receiverTypeName(recv *ast.FieldList) string {
+	if len(recv.List) == 0 {
+		return ""
+	}
+	expr := recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		expr = t.X
+	case *ast.IndexListExpr:
+		expr = t.X
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// diffSymbolDocs reports symbols, present on both sides, whose doc comment changed.
+// Nothing is reported unless both snapshots captured docs.
+func diffSymbolDocs(path string, oldPkg, newPkg *APIPackage) []APIDiffChange {
+	if oldPkg.Docs == nil || newPkg.Docs == nil {
+		return nil
+	}
+	oldKeys := make(map[string]bool)
+	for _, key := range symbolKeys(oldPkg) {
+		oldKeys[key] = true
+	}
+
+	var res []APIDiffChange
+	for _, key := range append([]string{""}, symbolKeys(newPkg)...) {
+		if key != "" && !oldKeys[key] {
+			continue
+		}
+		oldDoc := strings.Join(strings.Fields(oldPkg.Docs[key]), " ")
+		newDoc := strings.Join(strings.Fields(newPkg.Docs[key]), " ")
+		if oldDoc == newDoc {
+			continue
+		}
+		res = append(res, APIDiffChange{Label: "Docs", Path: path, Name: key, Old: oldDoc, New: newDoc})
+	}
+	return res
+}
+
+// writeDocsSection renders a word-level diff of every changed doc comment, grouped by package.
+func writeDocsSection(sb *strings.Builder, links *Links, heading string, items []APIDiffChange) {
+	if len(items) == 0 {
+		return
+	}
+	grouped := make(map[string]map[string][]string)
+	for _, c := range items {
+		if _, ok := grouped[c.Path]; !ok {
+			grouped[c.Path] = make(map[string][]string)
+		}
+		name := "`" + c.Name + "`"
+		if c.Name == "" {
+			name = "package"
+		}
+		key := "Changed " + c.Label
+		grouped[c.Path][key] = append(grouped[c.Path][key], links.withPosition(name, c.NewPos, false)+": "+wordDiff(c.Old, c.New, 3))
+	}
+	sb.WriteString("\n### " + heading + "\n")
+	writeGrouped(sb, grouped)
+}
+
+// wordDiff renders a compact word-level diff: removed words as ~~struck~~, added words in **bold**,
+// and unchanged runs shortened to ctx words around each change.
+func wordDiff(oldText, newText string, ctx int) string {
+	a, b := strings.Fields(oldText), strings.Fields(newText)
+
+	// longest common subsequence table, lcs[i][j] for a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type run struct {
+		op    byte // '=', '-', '+'
+		words []string
+	}
+	var runs []run
+	push := func(op byte, w string) {
+		if n := len(runs); n > 0 && runs[n-1].op == op {
+			runs[n-1].words = append(runs[n-1].words, w)
+			return
+		}
+		runs = append(runs, run{op: op, words: []string{w}})
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			push('=', a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			push('+', b[j])
+			j++
+		default:
+			push('-', a[i])
+			i++
+		}
+	}
+
+	parts := make([]string, 0, len(runs))
+	for k, r := range runs {
+		text := strings.Join(r.words, " ")
+		switch r.op {
+		case '-':
+			parts = append(parts, "~~"+text+"~~")
+		case '+':
+			parts = append(parts, "**"+text+"**")
+		default:
+			parts = append(parts, elide(r.words, ctx, k > 0, k < len(runs)-1))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// elide shortens an unchanged run, keeping ctx words next to the changes before and after it.
+// Runs are only shortened when that drops more than ctx words.
+func elide(words []string, ctx int, before, after bool) string {
+	keep := 0
+	if before {
+		keep += ctx
+	}
+	if after {
+		keep += ctx
+	}
+	if len(words) <= keep+ctx {
+		return strings.Join(words, " ")
+	}
+	var parts []string
+	if before {
+		parts = append(parts, words[:ctx]...)
+	}
+	parts = append(parts, "…")
+	if after {
+		parts = append(parts, words[len(words)-ctx:]...)
+	}
+	return strings.Join(parts, " ")
+}
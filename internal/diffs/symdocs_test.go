@@ -0,0 +1,97 @@
+package diffs
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "replaced words",
+			old:  "Get returns the value, or nil if the key is missing.",
+			new:  "Get returns the value, or ErrNotFound if the key is missing.",
+			want: "Get returns the value, or ~~nil~~ **ErrNotFound** if the key is missing.",
+		},
+		{
+			name: "long unchanged runs are elided",
+			old:  "one two three four five six seven eight nine ten eleven twelve",
+			new:  "one two three four five six seven eight nine ten eleven twelve thirteen",
+			want: "… ten eleven twelve **thirteen**",
+		},
+		{
+			name: "doc added",
+			old:  "",
+			new:  "Close releases resources.",
+			want: "**Close releases resources.**",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, wordDiff(tt.old, tt.new, 3))
+		})
+	}
+}
+
+func TestDiffAPI_Docs(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/kv": {
+			Funcs: []string{"Get(string) -> (any, error)", "Put(string, any)"},
+			Docs: map[string]string{
+				"Get": "Get returns the value,\nor nil if the key is missing.\n",
+				"Put": "Put stores a value.\n",
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/kv": {
+			Funcs: []string{"Get(string) -> (any, error)", "Put(string, any)"},
+			Docs: map[string]string{
+				"Get": "Get returns the value, or ErrNotFound if the key is missing.\n",
+				"Put": "Put stores a value.",
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	require.Len(t, apiDiff.DocsChanged, 1)
+	assert.Equal(t, "Get", apiDiff.DocsChanged[0].Name)
+
+	out := apiDiff.String()
+	assert.Contains(t, out, "### Documentation of exported API")
+	assert.Contains(t, out, "`Get`: Get returns the value, or ~~nil~~ **ErrNotFound** if the key is missing.")
+
+	// without docs captured on both sides there is nothing to compare
+	oldPkg := oldAPI["pkg/kv"]
+	oldPkg.Docs = nil
+	oldAPI["pkg/kv"] = oldPkg
+	assert.Empty(t, DiffAPI(oldAPI, newAPI).DocsChanged)
+}
+
+func TestSymbolDocs_PackageDoc(t *testing.T) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for i, src := range []string{
+		"// Package p does things.\npackage p\n",
+		"package p\n\n// F does one thing.\nfunc F() {}\n",
+		"// Package p is documented twice.\npackage p\n",
+	} {
+		f, err := parser.ParseFile(fset, fmt.Sprintf("f%d.go", i), src, parser.ParseComments)
+		require.NoError(t, err)
+		files = append(files, f)
+	}
+
+	docs := symbolDocs(files)
+	assert.Equal(t, "Package p does things.\n", docs[""], "the first package doc is kept")
+	assert.Equal(t, "F does one thing.\n", docs["F"])
+}
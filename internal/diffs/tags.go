@@ -0,0 +1,103 @@
+package diffs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// wireTagKeys are the struct tag keys that control how a field is serialized.
+var wireTagKeys = []string{"json", "yaml", "db", "xml", "toml"}
+
+// diffTags reports fields, present on both sides, whose struct tag changed.
+func diffTags(label, path string, oldTags, newTags map[string]string, oldFields, newFields []string) []APIDiffChange {
+	var res []APIDiffChange
+	names := make(map[string]bool)
+	for name := range oldTags {
+		names[name] = true
+	}
+	for name := range newTags {
+		names[name] = true
+	}
+	for name := range names {
+		if !containsName(oldFields, name) || !containsName(newFields, name) {
+			continue
+		}
+		if oldTags[name] == newTags[name] {
+			continue
+		}
+		res = append(res, APIDiffChange{
+			Label: label,
+			Path:  path,
+			Name:  name,
+			Old:   strings.TrimSpace(name + " " + oldTags[name]),
+			New:   strings.TrimSpace(name + " " + newTags[name]),
+		})
+	}
+	return res
+}
+
+// compatTags flags tag changes that alter the wire format: a renamed key, "-", or omitempty.
+// Other tag changes (e.g. validation rules) are reported as compatible.
+func compatTags(oldTags, newTags map[string]string, changed []APIDiffChange) []APICompatChange {
+	res := make([]APICompatChange, 0, len(changed))
+	for i := range changed {
+		c := &changed[i]
+		reasons := wireChanges(reflect.StructTag(oldTags[c.Name]), reflect.StructTag(newTags[c.Name]))
+		if len(reasons) == 0 {
+			res = append(res, compatChanged(c.Label, c, true, "tag changed without affecting serialization"))
+			continue
+		}
+		res = append(res, compatChanged(c.Label, c, false, "wire format changed: "+strings.Join(reasons, "; ")))
+	}
+	return res
+}
+
+func wireChanges(oldTag, newTag reflect.StructTag) []string {
+	var reasons []string
+	for _, key := range wireTagKeys {
+		oldVal, oldOK := oldTag.Lookup(key)
+		newVal, newOK := newTag.Lookup(key)
+		if !oldOK && !newOK {
+			continue
+		}
+		oldName, oldOpts := parseTagValue(oldVal)
+		newName, newOpts := parseTagValue(newVal)
+
+		switch {
+		case oldName == "-" && newName != "-":
+			reasons = append(reasons, fmt.Sprintf("%s: field is now serialized", key))
+		case oldName != "-" && newName == "-":
+			reasons = append(reasons, fmt.Sprintf("%s: field is no longer serialized", key))
+		case oldName != newName:
+			reasons = append(reasons, fmt.Sprintf("%s key %s → %s", key, quoteKey(oldName), quoteKey(newName)))
+		}
+		if oldOpts["omitempty"] != newOpts["omitempty"] {
+			if newOpts["omitempty"] {
+				reasons = append(reasons, fmt.Sprintf("%s: omitempty added", key))
+			} else {
+				reasons = append(reasons, fmt.Sprintf("%s: omitempty removed", key))
+			}
+		}
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+func parseTagValue(v string) (name string, opts map[string]bool) {
+	parts := strings.Split(v, ",")
+	opts = make(map[string]bool)
+	for _, o := range parts[1:] {
+		opts[strings.TrimSpace(o)] = true
+	}
+	return parts[0], opts
+}
+
+// quoteKey renders a serialization key; an empty key means the Go field name is used.
+func quoteKey(name string) string {
+	if name == "" {
+		return "(field name)"
+	}
+	return fmt.Sprintf("%q", name)
+}
@@ -0,0 +1,72 @@
+package diffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAPI_FieldTags(t *testing.T) {
+	oldAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Types: map[string]APIType{
+				"User": {
+					Kind:   "struct",
+					Fields: []string{"ID int", "Name string", "Email string", "Secret string", "Age int"},
+					Tags: map[string]string{
+						"ID":     `json:"user_id" db:"id"`,
+						"Name":   `json:"name" validate:"required"`,
+						"Email":  `json:"email"`,
+						"Secret": `json:"secret"`,
+					},
+				},
+			},
+		},
+	}
+	newAPI := map[string]APIPackage{
+		"pkg/mypkg": {
+			Types: map[string]APIType{
+				"User": {
+					Kind:   "struct",
+					Fields: []string{"ID int", "Name string", "Email string", "Secret string", "Age int"},
+					Tags: map[string]string{
+						"ID":     `json:"userId" db:"id"`,
+						"Name":   `json:"name" validate:"required,min=1"`,
+						"Email":  `json:"email,omitempty"`,
+						"Secret": `json:"-"`,
+						"Age":    `json:"age"`,
+					},
+				},
+			},
+		},
+	}
+
+	apiDiff := DiffAPI(oldAPI, newAPI)
+	require.Len(t, apiDiff.FieldTagsChanged, 5)
+
+	reasons := make(map[string]APICompatChange)
+	for _, c := range apiDiff.Compat {
+		if c.Kind == "Changed Type `User` Field Tags" {
+			reasons[c.Symbol] = c
+		}
+	}
+
+	id := reasons[`ID json:"user_id" db:"id" → ID json:"userId" db:"id"`]
+	assert.False(t, id.Compatible)
+	assert.Equal(t, `wire format changed: json key "user_id" → "userId"`, id.Reason)
+
+	assert.True(t, reasons[`Name json:"name" validate:"required" → Name json:"name" validate:"required,min=1"`].Compatible)
+
+	email := reasons[`Email json:"email" → Email json:"email,omitempty"`]
+	assert.False(t, email.Compatible)
+	assert.Contains(t, email.Reason, "omitempty added")
+
+	secret := reasons[`Secret json:"secret" → Secret json:"-"`]
+	assert.False(t, secret.Compatible)
+	assert.Contains(t, secret.Reason, "no longer serialized")
+
+	age := reasons[`Age → Age json:"age"`]
+	assert.False(t, age.Compatible)
+	assert.Contains(t, age.Reason, `json key (field name) → "age"`)
+}
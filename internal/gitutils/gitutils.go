@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"strings"
 )
 
 func CheckoutWorktree(repoDir, ref string) string {
@@ -20,6 +21,17 @@ func CleanupWorktree(repoDir, path string) {
 	runGitInDir(repoDir, "worktree", "remove", "--force", path)
 }
 
+// ResolveRef returns the commit SHA a ref points to.
+func ResolveRef(repoDir, ref string) string {
+	cmd := exec.Command("git", "rev-parse", "--verify", ref+"^{commit}")
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		log.Fatalf("cannot resolve %s: %v", ref, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
 func runGitInDir(dir string, args ...string) {
 	cmd := exec.Command("git", args...)
 	cmd.Dir = dir
@@ -29,3 +41,31 @@ func runGitInDir(dir string, args ...string) {
 		log.Fatalf("git %v failed: %v", args, err)
 	}
 }
+
+// RefsByCommit maps commit SHAs to the branches, tags and HEAD of repoDir that resolve to them.
+// Outside a git repository it returns an empty map.
+func RefsByCommit(repoDir string) map[string][]string {
+	res := make(map[string][]string)
+	cmd := exec.Command("git", "for-each-ref", "--format=%(objectname) %(*objectname) %(refname:short)", "refs/heads", "refs/tags", "refs/remotes")
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return res
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 2: // branch or lightweight tag
+			res[fields[0]] = append(res[fields[0]], fields[1])
+		case 3: // annotated tag: the commit it peels to
+			res[fields[1]] = append(res[fields[1]], fields[2])
+		}
+	}
+	cmd = exec.Command("git", "rev-parse", "--verify", "-q", "HEAD")
+	cmd.Dir = repoDir
+	if out, err := cmd.Output(); err == nil {
+		sha := strings.TrimSpace(string(out))
+		res[sha] = append([]string{"HEAD"}, res[sha]...)
+	}
+	return res
+}
@@ -53,3 +53,18 @@ func TestCheckoutAndCleanupWorktree(t *testing.T) {
 	require.Error(t, err, "worktree dir should be removed")
 	require.True(t, os.IsNotExist(err), "worktree dir should be removed")
 }
+
+func TestRefsByCommit(t *testing.T) {
+	tmpDir := t.TempDir()
+	testutils.RunGit(t, tmpDir, "init", "-b", "main")
+	testutils.RunGit(t, tmpDir, "config", "user.name", "Test User")
+	testutils.RunGit(t, tmpDir, "config", "user.email", "test@example.com")
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "file.txt"), []byte("hello"), 0o600))
+	testutils.RunGit(t, tmpDir, "add", "file.txt")
+	testutils.RunGit(t, tmpDir, "commit", "-m", "initial commit")
+	testutils.RunGit(t, tmpDir, "tag", "-a", "v1", "-m", "v1")
+
+	sha := ResolveRef(tmpDir, "HEAD")
+	require.Equal(t, map[string][]string{sha: {"HEAD", "main", "v1"}}, RefsByCommit(tmpDir))
+	require.Empty(t, RefsByCommit(t.TempDir()))
+}
@@ -0,0 +1,52 @@
+package gitutils
+
+import (
+	"errors"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// remoteNotesRef is where FetchNotes puts the notes of remote before merging them, e.g.
+// refs/notes/remotes/origin/relimpact for refs/notes/relimpact.
+func remoteNotesRef(remote, ref string) string {
+	return "refs/notes/remotes/" + remote + "/" + strings.TrimPrefix(ref, "refs/notes/")
+}
+
+// FetchNotes fetches the notes ref from remote and merges it into the local one, keeping the local
+// content of notes attached to the same commit on both sides. It returns the ref the remote notes were
+// fetched to, so that their content can be merged further, or false when remote has no such ref.
+func FetchNotes(repoDir, remote, ref string) (string, bool) {
+	cmd := exec.Command("git", "ls-remote", "--exit-code", remote, ref)
+	cmd.Dir = repoDir
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 2 {
+			return "", false
+		}
+		log.Fatalf("git ls-remote %s failed: %v", remote, err)
+	}
+
+	tracking := remoteNotesRef(remote, ref)
+	runGitInDir(repoDir, "fetch", remote, "+"+ref+":"+tracking)
+	if !refExists(repoDir, ref) {
+		runGitInDir(repoDir, "update-ref", ref, tracking)
+		return tracking, true
+	}
+	runGitInDir(repoDir, "notes", "--ref", ref, "merge", "--quiet", "--strategy", "ours", tracking)
+	return tracking, true
+}
+
+// PushNotes pushes the notes ref to remote. Fetch first, so that the push fast-forwards.
+func PushNotes(repoDir, remote, ref string) {
+	if !refExists(repoDir, ref) {
+		log.Fatalf("nothing to push: %s does not exist", ref)
+	}
+	runGitInDir(repoDir, "push", remote, ref+":"+ref)
+}
+
+func refExists(repoDir, ref string) bool {
+	cmd := exec.Command("git", "rev-parse", "--verify", "-q", ref)
+	cmd.Dir = repoDir
+	return cmd.Run() == nil
+}
@@ -0,0 +1,73 @@
+package gitutils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashmap-kz/relimpact/internal/testutils"
+	"github.com/stretchr/testify/require"
+)
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	require.NoError(t, err, "git %v", args)
+	return strings.TrimSpace(string(out))
+}
+
+func TestPushAndFetchNotes(t *testing.T) {
+	const ref = "refs/notes/relimpact"
+	remote := t.TempDir()
+	testutils.RunGit(t, remote, "init", "--bare")
+
+	clone := func() string {
+		dir := t.TempDir()
+		testutils.RunGit(t, dir, "init")
+		testutils.RunGit(t, dir, "config", "user.name", "Test User")
+		testutils.RunGit(t, dir, "config", "user.email", "test@example.com")
+		testutils.RunGit(t, dir, "remote", "add", "origin", remote)
+		return dir
+	}
+
+	a := clone()
+	require.NoError(t, os.WriteFile(filepath.Join(a, "file.txt"), []byte("hello"), 0o600))
+	testutils.RunGit(t, a, "add", "file.txt")
+	testutils.RunGit(t, a, "commit", "-m", "initial commit")
+	testutils.RunGit(t, a, "push", "origin", "HEAD:refs/heads/main")
+	first := ResolveRef(a, "HEAD")
+
+	b := clone()
+	testutils.RunGit(t, b, "fetch", "origin", "main:refs/remotes/origin/main")
+	_, ok := FetchNotes(b, "origin", ref)
+	require.False(t, ok, "nothing published yet")
+
+	testutils.RunGit(t, a, "notes", "--ref", ref, "add", "-m", "snapshot a", first)
+	PushNotes(a, "origin", ref)
+
+	tracking, ok := FetchNotes(b, "origin", ref)
+	require.True(t, ok)
+	require.Equal(t, "refs/notes/remotes/origin/relimpact", tracking)
+	require.Equal(t, "snapshot a", gitOutput(t, b, "notes", "--ref", ref, "show", first))
+
+	// b annotates another commit and publishes
+	testutils.RunGit(t, b, "checkout", "-q", "origin/main")
+	testutils.RunGit(t, b, "commit", "--allow-empty", "-m", "second")
+	testutils.RunGit(t, b, "push", "origin", "HEAD:refs/heads/main")
+	second := ResolveRef(b, "HEAD")
+	testutils.RunGit(t, b, "notes", "--ref", ref, "add", "-m", "snapshot b", second)
+	PushNotes(b, "origin", ref)
+
+	// a diverged meanwhile: fetching merges b's notes and keeps a's, so the push fast-forwards
+	testutils.RunGit(t, a, "notes", "--ref", ref, "add", "-f", "-m", "snapshot a2", first)
+	_, ok = FetchNotes(a, "origin", ref)
+	require.True(t, ok)
+	PushNotes(a, "origin", ref)
+	testutils.RunGit(t, a, "fetch", "origin", "main")
+	require.Equal(t, "snapshot b", gitOutput(t, a, "notes", "--ref", ref, "show", second))
+	require.Equal(t, "snapshot a2", gitOutput(t, a, "notes", "--ref", ref, "show", first))
+}
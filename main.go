@@ -1,32 +1,252 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/hashmap-kz/relimpact/internal/diffs"
 	"github.com/hashmap-kz/relimpact/internal/loggr"
 
 	"github.com/hashmap-kz/relimpact/cmd"
 )
 
 func main() {
+	// TODO: log level (envs, CLI)
+	loggr.Init(loggr.LevelTrace, "relimpact")
+
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		runVersion(os.Args[2:])
+		cmd.CapCache()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "notes" {
+		runNotes(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "snapshot" {
+		runSnapshot(os.Args[2:])
+		cmd.CapCache()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "diff-api" {
+		runDiffAPI(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		runCache(os.Args[2:])
+		return
+	}
+
 	oldRef := flag.String("old", "", "Old git ref")
-	newRef := flag.String("new", "", "New git ref")
+	newRef := flag.String("new", "", "New git ref; omit to compare against the working tree, uncommitted changes included")
 	greedy := flag.Bool("greedy", false, "Maximum concurrency")
+	goSyntax := flag.Bool("go-syntax", false, "Render funcs and methods as Go declarations, with parameter names")
+	classes := classFlags(flag.CommandLine)
+	docs := flag.Bool("docs", false, "Report word-level diffs of changed doc comments of exported symbols")
+	strict := flag.Bool("strict", false, "Fail when a package cannot be loaded, instead of listing it as not analysed")
+	notes := flag.Bool("notes", false, "Read and store API snapshots in git notes ("+diffs.NotesRef+")")
+	linkTemplate := flag.String("link-template", "", "Link API changes to their source, e.g. https://github.com/org/repo/blob/{ref}/{file}#L{line}")
+	var platforms []diffs.Platform
+	flag.Func("platform", "Snapshot the API on GOOS/GOARCH[:tags] (repeatable)", platformFlag(&platforms))
+	var interfaces []diffs.WellKnownInterface
+	flag.Func("interface", "Check types against this interface instead of the default stdlib set: a default name like io.Reader, "+
+		"or name=Method(sig) -> (res);... (repeatable)", interfaceFlag(&interfaces))
 	flag.Parse()
 
-	if *oldRef == "" || *newRef == "" {
-		_, _ = fmt.Fprintf(os.Stderr, "Usage: relimpact --old <ref> --new <ref>")
+	if *oldRef == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage: relimpact --old <ref> [--new <ref>]")
 		os.Exit(1)
 	}
 
-	// TODO: log level (envs, CLI)
-	loggr.Init(loggr.LevelTrace, "relimpact")
-
+	opts := cmd.Options{GoSyntax: *goSyntax, Platforms: platforms, Docs: *docs, Interfaces: interfaces, Strict: *strict, LinkTemplate: *linkTemplate, Notes: *notes, Classes: classes()}
 	if *greedy {
-		fmt.Println(cmd.CreateChangelog(".", *oldRef, *newRef))
+		fmt.Println(cmd.CreateChangelog(".", *oldRef, *newRef, opts))
 	} else {
-		fmt.Println(cmd.CreateChangelogSequential(".", *oldRef, *newRef))
+		fmt.Println(cmd.CreateChangelogSequential(".", *oldRef, *newRef, opts))
+	}
+	cmd.CapCache()
+}
+
+// runVersion implements 'relimpact version': suggest the next semver after --old.
+func runVersion(args []string) {
+	usage := "Usage: relimpact version --old <tag> [--new <ref>] [--json]\n" +
+		"Only the module at the repository root is snapshotted; nested modules, tagged <dir>/vX.Y.Z, are not taken into account.\n"
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprint(os.Stderr, usage)
+		fs.PrintDefaults()
+	}
+	oldRef := fs.String("old", "", "Old git ref (a semver tag of the root module, e.g. v1.2.0)")
+	newRef := fs.String("new", "HEAD", "New git ref")
+	asJSON := fs.Bool("json", false, "Print the suggestion as JSON")
+	notes := fs.Bool("notes", false, "Read and store API snapshots in git notes ("+diffs.NotesRef+")")
+	var platforms []diffs.Platform
+	fs.Func("platform", "Snapshot the API on GOOS/GOARCH[:tags] (repeatable)", platformFlag(&platforms))
+	_ = fs.Parse(args)
+
+	if *oldRef == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	suggestion := cmd.SuggestVersion(".", *oldRef, *newRef, cmd.Options{Platforms: platforms, Notes: *notes})
+	if *asJSON {
+		data, err := json.MarshalIndent(suggestion, "", "  ")
+		if err != nil {
+			loggr.Fatalf("cannot encode suggestion: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	fmt.Print(suggestion.String())
+}
+
+// runSnapshot implements 'relimpact snapshot': export the API at --ref for a later 'relimpact diff-api'.
+func runSnapshot(args []string) {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	ref := fs.String("ref", "HEAD", "Git ref to snapshot")
+	out := fs.String("out", "-", "File to write the snapshot to, - for stdout")
+	docs := fs.Bool("docs", false, "Capture doc comments of exported symbols")
+	strict := fs.Bool("strict", false, "Fail when a package cannot be loaded")
+	notes := fs.Bool("notes", false, "Read and store API snapshots in git notes ("+diffs.NotesRef+")")
+	var platforms []diffs.Platform
+	fs.Func("platform", "Snapshot the API on GOOS/GOARCH[:tags] (repeatable)", platformFlag(&platforms))
+	var interfaces []diffs.WellKnownInterface
+	fs.Func("interface", "Check types against this interface instead of the default stdlib set (repeatable)", interfaceFlag(&interfaces))
+	_ = fs.Parse(args)
+
+	opts := cmd.Options{Platforms: platforms, Docs: *docs, Interfaces: interfaces, Strict: *strict, Notes: *notes}
+	if err := diffs.WriteSnapshotFile(*out, cmd.ExportSnapshot(".", *ref, opts)); err != nil {
+		loggr.Fatalf("cannot write snapshot: %v", err)
+	}
+}
+
+// runDiffAPI implements 'relimpact diff-api': the API section of the changelog from two exported snapshots.
+func runDiffAPI(args []string) {
+	fs := flag.NewFlagSet("diff-api", flag.ExitOnError)
+	oldPath := fs.String("old", "", "Old snapshot file")
+	newPath := fs.String("new", "", "New snapshot file")
+	goSyntax := fs.Bool("go-syntax", false, "Render funcs and methods as Go declarations, with parameter names")
+	strict := fs.Bool("strict", false, "Fail when a snapshot has packages that could not be loaded")
+	linkTemplate := fs.String("link-template", "", "Link API changes to their source at the commits recorded in the snapshots")
+	classes := classFlags(fs)
+	_ = fs.Parse(args)
+
+	if *oldPath == "" || *newPath == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage: relimpact diff-api --old <file> --new <file>")
+		os.Exit(1)
+	}
+	opts := cmd.Options{GoSyntax: *goSyntax, Strict: *strict, LinkTemplate: *linkTemplate, Classes: classes()}
+	fmt.Println(cmd.DiffSnapshots(*oldPath, *newPath, opts))
+}
+
+// classFlags registers the flags selecting the non-public package classes to report.
+func classFlags(fs *flag.FlagSet) func() []string {
+	internal := fs.Bool("internal", true, "Report changes of internal/ packages in their own section")
+	commands := fs.Bool("commands", true, "Report changes of package main commands in their own section")
+	examples := fs.Bool("examples", false, "Report changes of example packages in their own section")
+	return func() []string {
+		var classes []string
+		if *internal {
+			classes = append(classes, diffs.ClassInternal)
+		}
+		if *commands {
+			classes = append(classes, diffs.ClassCommand)
+		}
+		if *examples {
+			classes = append(classes, diffs.ClassExample)
+		}
+		return classes
+	}
+}
+
+// runNotes implements 'relimpact notes push|fetch': share the snapshots stored in git notes through a remote.
+func runNotes(args []string) {
+	usage := "Usage: relimpact notes push|fetch [--remote <name>]"
+	if len(args) == 0 || (args[0] != "push" && args[0] != "fetch") {
+		_, _ = fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("notes "+args[0], flag.ExitOnError)
+	remote := fs.String("remote", "origin", "Remote to push to or fetch from")
+	_ = fs.Parse(args[1:])
+
+	if args[0] == "push" {
+		fmt.Print(cmd.PublishNotes(".", *remote))
+		return
+	}
+	fmt.Print(cmd.FetchNotes(".", *remote))
+}
+
+// runCache implements 'relimpact cache ls|prune|verify|clear': inspect and clean up the API snapshot cache.
+func runCache(args []string) {
+	usage := "Usage: relimpact cache ls | prune [--max-age <duration>] [--max-size <size>] | verify [--remove] | clear"
+	if len(args) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("cache "+args[0], flag.ExitOnError)
+	switch args[0] {
+	case "ls":
+		_ = fs.Parse(args[1:])
+		fmt.Print(cmd.CacheList(".", time.Now()))
+	case "prune":
+		maxAge := fs.Duration("max-age", 0, "Remove entries not used for longer than this, e.g. 720h")
+		maxSize := fs.String("max-size", "", "Then remove the least recently used entries until the cache fits, e.g. 500MB")
+		_ = fs.Parse(args[1:])
+		policy := diffs.CachePrunePolicy{MaxAge: *maxAge}
+		if *maxSize != "" {
+			size, err := diffs.ParseSize(*maxSize)
+			if err != nil {
+				loggr.Fatalf("--max-size: %v", err)
+			}
+			policy.MaxSize = size
+		}
+		if policy.MaxAge == 0 && policy.MaxSize == 0 {
+			_, _ = fmt.Fprintln(os.Stderr, "relimpact cache prune: set --max-age, --max-size or both")
+			os.Exit(1)
+		}
+		fmt.Print(cmd.CachePrune(policy, time.Now()))
+	case "verify":
+		remove := fs.Bool("remove", false, "Remove invalid entries")
+		_ = fs.Parse(args[1:])
+		report, ok := cmd.CacheVerify(*remove)
+		fmt.Print(report)
+		if !ok {
+			os.Exit(1)
+		}
+	case "clear":
+		_ = fs.Parse(args[1:])
+		fmt.Print(cmd.CacheClear())
+	default:
+		_, _ = fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+}
+
+// platformFlag collects repeated --platform values.
+func platformFlag(platforms *[]diffs.Platform) func(string) error {
+	return func(s string) error {
+		p, err := diffs.ParsePlatform(s)
+		if err != nil {
+			return err
+		}
+		*platforms = append(*platforms, p)
+		return nil
+	}
+}
+
+func interfaceFlag(interfaces *[]diffs.WellKnownInterface) func(string) error {
+	return func(s string) error {
+		iface, err := diffs.ParseInterface(s)
+		if err != nil {
+			return err
+		}
+		*interfaces = append(*interfaces, iface)
+		return nil
 	}
 }